@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"warpp/internal/config"
+	"warpp/internal/daemon"
+	"warpp/internal/history"
+	"warpp/internal/tmux"
+)
+
+// Exit codes of the scripting commands
+const (
+	exitOK         = 0
+	exitError      = 1 // tmux or another command failed
+	exitUsage      = 2 // bad arguments
+	exitNotFound   = 3 // no session or layout by that name
+	exitNotRunning = 4 // a layout exists but its session isn't running
+)
+
+// sessionJSON is the stable JSON form of a session or layout printed by ls
+// and status. Fields are only ever added.
+type sessionJSON struct {
+	Name         string      `json:"name"`
+	Running      bool        `json:"running"`
+	Layout       bool        `json:"layout"` // a layout exists for it
+	Description  string      `json:"description,omitempty"`
+	ProjectRoot  string      `json:"project_root,omitempty"`
+	ProjectType  string      `json:"project_type,omitempty"`
+	Source       string      `json:"source,omitempty"` // tmuxifier or warpp, for layouts
+	LayoutPath   string      `json:"layout_path,omitempty"`
+	LayoutIssues []string    `json:"layout_issues,omitempty"`
+	Windows      int         `json:"windows,omitempty"`
+	Attached     int         `json:"attached,omitempty"`
+	Created      *time.Time  `json:"created,omitempty"`
+	Activity     *time.Time  `json:"activity,omitempty"`
+	Alerts       string      `json:"alerts,omitempty"`
+	Agents       []agentJSON `json:"agents"`
+}
+
+// agentJSON is the stable JSON form of a coding agent in a pane
+type agentJSON struct {
+	Agent  string `json:"agent"`
+	State  string `json:"state"` // waiting, executing or idle
+	Pane   int    `json:"pane"`
+	PaneID string `json:"pane_id"`
+}
+
+func toSessionJSON(s tmux.Session) sessionJSON {
+	out := sessionJSON{
+		Name:         s.Name,
+		Running:      s.IsRunning,
+		Layout:       s.IsLayout || s.LayoutPath != "",
+		Description:  s.Description,
+		ProjectRoot:  s.ProjectRoot,
+		ProjectType:  s.ProjectType,
+		Source:       s.Source,
+		LayoutPath:   s.LayoutPath,
+		LayoutIssues: s.LayoutIssues,
+		Windows:      s.Windows,
+		Attached:     s.Attached,
+		Alerts:       s.Alerts,
+		Agents:       []agentJSON{},
+	}
+	if !s.Created.IsZero() {
+		out.Created = &s.Created
+	}
+	if !s.Activity.IsZero() {
+		out.Activity = &s.Activity
+	}
+	for _, a := range s.Agents {
+		out.Agents = append(out.Agents, agentJSON{Agent: a.Agent, State: a.State, Pane: a.Pane, PaneID: a.PaneID})
+	}
+	return out
+}
+
+// cliError is an error with the exit code it maps to. Without err the
+// command has already said what went wrong.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func usageError(format string, args ...any) error {
+	return &cliError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+func notFoundError(name string) error {
+	return &cliError{code: exitNotFound, err: fmt.Errorf("no session or layout named %s", name)}
+}
+
+func notRunningError(name string) error {
+	return &cliError{code: exitNotRunning, err: fmt.Errorf("session %s is not running", name)}
+}
+
+// exitCode maps an error from a command to its exit code
+func exitCode(err error) int {
+	var cliErr *cliError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &cliErr):
+		return cliErr.code
+	case errors.Is(err, tmux.ErrSessionNotFound):
+		return exitNotFound
+	}
+	return exitError
+}
+
+// runCLI runs a scripting command and exits with its code
+func runCLI(run func(args []string) error, args []string) {
+	err := run(args)
+	var cliErr *cliError
+	if err != nil && !(errors.As(err, &cliErr) && cliErr.err == nil) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// parseFlags splits args into --flags and positional arguments. Flags in
+// valueFlags take a value, as --flag value or --flag=value; the others are
+// booleans. Flags not in allowed are a usage error.
+func parseFlags(args []string, allowed []string, valueFlags ...string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !contains(allowed, name) {
+			return nil, nil, usageError("unknown flag --%s", name)
+		}
+		if contains(valueFlags, name) && !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, usageError("--%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		} else if !hasValue {
+			value = "true"
+		}
+		flags[name] = value
+	}
+	return flags, positional, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCLISessions lists running sessions and layouts from the daemon when
+// it's running, and otherwise from tmux
+func loadCLISessions(ctx context.Context) ([]tmux.Session, error) {
+	if sessions, err := daemon.NewClient().List(ctx); err == nil {
+		return sessions, nil
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	setupAgents(cfg)
+	return tmux.GetAllSessions(ctx, tmux.NewExecBackend())
+}
+
+// findSession returns the running session called name, or else its layout
+func findSession(sessions []tmux.Session, name string) (tmux.Session, bool) {
+	for _, s := range sessions {
+		if s.Name == name && (s.IsRunning || s.IsLayout) {
+			return s, true
+		}
+	}
+	return tmux.Session{}, false
+}
+
+// printJSON writes v as indented JSON to stdout
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// cmdLs lists sessions and layouts: warpp ls [--json] [--running|--layouts]
+func cmdLs(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"json", "running", "layouts"})
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || (flags["running"] != "" && flags["layouts"] != "") {
+		return usageError("usage: warpp ls [--json] [--running|--layouts]")
+	}
+
+	sessions, err := loadCLISessions(context.Background())
+	if err != nil {
+		return err
+	}
+	var listed []tmux.Session
+	for _, s := range sessions {
+		switch {
+		case flags["running"] != "" && !s.IsRunning,
+			flags["layouts"] != "" && !s.IsLayout,
+			!s.IsRunning && !s.IsLayout:
+			continue
+		}
+		listed = append(listed, s)
+	}
+
+	if flags["json"] != "" {
+		out := []sessionJSON{}
+		for _, s := range listed {
+			out = append(out, toSessionJSON(s))
+		}
+		return printJSON(out)
+	}
+	now := time.Now()
+	for _, s := range listed {
+		if !s.IsRunning {
+			fmt.Printf("%-20s layout   %s\n", s.Name, s.Description)
+			continue
+		}
+		agent := ""
+		if top, ok := s.TopAgent(); ok {
+			agent = top.Agent + " " + top.State
+		}
+		fmt.Printf("%-20s running  %2dw %4s %s\n", s.Name, s.Windows, relativeTime(s.Activity, now), agent)
+	}
+	return nil
+}
+
+// cmdAttach attaches to a session, starting it from its layout first if
+// needed: warpp attach <name>
+func cmdAttach(args []string) error {
+	if len(args) != 1 {
+		return usageError("usage: warpp attach <name>")
+	}
+	sessions, err := loadCLISessions(context.Background())
+	if err != nil {
+		return err
+	}
+	session, ok := findSession(sessions, args[0])
+	if !ok {
+		return notFoundError(args[0])
+	}
+	return launchSession(session)
+}
+
+// cmdStart starts a layout's session without attaching: warpp start <layout>.
+// A session that's already running is left as it is.
+func cmdStart(args []string) error {
+	if len(args) != 1 {
+		return usageError("usage: warpp start <layout>")
+	}
+	ctx := context.Background()
+	sessions, err := loadCLISessions(ctx)
+	if err != nil {
+		return err
+	}
+	session, ok := findSession(sessions, args[0])
+	if !ok {
+		return notFoundError(args[0])
+	}
+	if session.IsRunning {
+		fmt.Fprintf(os.Stderr, "%s is already running\n", session.Name)
+		return nil
+	}
+
+	err = daemon.NewClient().Launch(ctx, session.Name)
+	if !errors.Is(err, daemon.ErrUnavailable) {
+		return err
+	}
+	if err := tmux.StartLayout(ctx, session, "", ""); err != nil {
+		return err
+	}
+	// Best-effort, like attaching
+	history.Record(session.Name)
+	return nil
+}
+
+// cmdKill kills running sessions: warpp kill <name...>. It carries on past
+// names that aren't running. Killed sessions go to the trash first.
+func cmdKill(args []string) error {
+	if len(args) == 0 {
+		return usageError("usage: warpp kill <name...>")
+	}
+	sessions, err := loadCLISessions(context.Background())
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	// Agents are detected so the trash knows what to offer to resume
+	setupAgents(cfg)
+
+	backend := tmux.NewExecBackend()
+	client := daemon.NewClient()
+	var failed error
+	for _, name := range args {
+		session, ok := findSession(sessions, name)
+		if !ok || !session.IsRunning {
+			err = notRunningError(name)
+			if !ok {
+				err = notFoundError(name)
+			}
+		} else {
+			err = killSession(backend, client, name, cfg.Snapshot.Trash)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = err
+		}
+	}
+	if failed != nil {
+		return &cliError{code: exitCode(failed)}
+	}
+	return nil
+}
+
+// cmdStatus reports whether a session is running and what its agents do:
+// warpp status <name> [--json]. It exits 0 when running and exitNotRunning
+// when only its layout exists.
+func cmdStatus(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"json"})
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("usage: warpp status <name> [--json]")
+	}
+	sessions, err := loadCLISessions(context.Background())
+	if err != nil {
+		return err
+	}
+	session, ok := findSession(sessions, positional[0])
+	if !ok {
+		return notFoundError(positional[0])
+	}
+
+	if flags["json"] != "" {
+		if err := printJSON(toSessionJSON(session)); err != nil {
+			return err
+		}
+	} else if session.IsRunning {
+		fmt.Printf("%s: running, %d windows, %d attached\n", session.Name, session.Windows, session.Attached)
+		for _, agent := range session.Agents {
+			fmt.Printf("  pane %d (%s): %s %s\n", agent.Pane, agent.PaneID, agent.Agent, agent.State)
+		}
+	} else {
+		fmt.Printf("%s: not running\n", session.Name)
+	}
+	if !session.IsRunning {
+		return &cliError{code: exitNotRunning}
+	}
+	return nil
+}
+
+// cmdPreview prints the visible content of a session's pane:
+// warpp preview <name> [--pane N] [--json]. Without --pane it's the active
+// pane; N counts panes of the current window.
+func cmdPreview(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"pane", "json"}, "pane")
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("usage: warpp preview <name> [--pane N] [--json]")
+	}
+	pane := tmux.ActivePane
+	if value, ok := flags["pane"]; ok {
+		if pane, err = strconv.Atoi(value); err != nil || pane < 0 {
+			return usageError("--pane: expected a pane index, got %q", value)
+		}
+	}
+
+	ctx := context.Background()
+	sessions, err := loadCLISessions(ctx)
+	if err != nil {
+		return err
+	}
+	session, ok := findSession(sessions, positional[0])
+	if !ok {
+		return notFoundError(positional[0])
+	}
+	if !session.IsRunning {
+		return notRunningError(session.Name)
+	}
+
+	content, err := tmux.NewExecBackend().CapturePane(ctx, session.Name, pane, 0, false)
+	if err != nil {
+		return err
+	}
+	// Drop the empty rows below the last output
+	content = strings.TrimRight(content, "\n") + "\n"
+	if flags["json"] != "" {
+		return printJSON(struct {
+			Session string `json:"session"`
+			Pane    *int   `json:"pane"` // null for the active pane
+			Content string `json:"content"`
+		}{session.Name, paneOrNil(pane), content})
+	}
+	fmt.Print(content)
+	return nil
+}
+
+func paneOrNil(pane int) *int {
+	if pane == tmux.ActivePane {
+		return nil
+	}
+	return &pane
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"warpp/internal/tmux"
+)
+
+// demoMessage is shown instead of launching or killing in demo mode
+const demoMessage = "Demo mode: sessions aren't launched or killed"
+
+// demoBackend returns an in-memory tmux server with a few sessions and
+// coding agents, so warpp --demo can be tried and screenshotted without a
+// tmux server
+func demoBackend() *tmux.FakeBackend {
+	b := tmux.NewFakeBackend()
+	now := time.Now()
+
+	b.AddSession("api", tmux.PaneInfo{Index: 0, Width: 120, Height: 40})
+	b.SetPaneContent("api", 0, `╭───────────────────────────────────────────╮
+│ ✻ Welcome to Claude Code!                 │
+╰───────────────────────────────────────────╯
+
+> add rate limiting to the /login handler
+
+● I'll add a token bucket limiter in middleware/ratelimit.go
+  and wire it into the router.
+
+╭───────────────────────────────────────────╮
+│ Edit file                                 │
+│ middleware/ratelimit.go                   │
+│                                           │
+│ Do you want to make this edit?            │
+│ ❯ 1. Yes                                  │
+│   2. No, and tell Claude what to do       │
+╰───────────────────────────────────────────╯`)
+	b.AddProcess("api", 0, "claude")
+	b.SetSessionInfo("api", tmux.SessionInfo{Created: now.Add(-26 * time.Hour), Activity: now.Add(-2 * time.Minute), Windows: 2})
+
+	b.AddSession("web",
+		tmux.PaneInfo{Index: 0, Left: 0, Top: 0, Width: 60, Height: 40, Active: true},
+		tmux.PaneInfo{Index: 1, Left: 61, Top: 0, Width: 59, Height: 20},
+		tmux.PaneInfo{Index: 2, Left: 61, Top: 21, Width: 59, Height: 19},
+	)
+	b.SetPaneContent("web", 0, `$ nvim src/App.tsx
+import { Router } from "./router"
+import { Header } from "./components/Header"
+
+export default function App() {
+  return (
+    <Router>
+      <Header />
+    </Router>
+  )
+}`)
+	b.SetPaneContent("web", 1, `$ npm run dev
+
+  VITE v5.4.2  ready in 312 ms
+
+  ➜  Local:   http://localhost:5173/
+  ➜  Network: use --host to expose`)
+	b.SetPaneContent("web", 2, `› fix the failing Header snapshot test
+
+• Running npm test -- Header (esc to interrupt)`)
+	b.AddProcess("web", 0, "nvim src/App.tsx")
+	b.AddProcess("web", 1, "node vite")
+	b.AddProcess("web", 2, "codex")
+	b.SetSessionInfo("web", tmux.SessionInfo{Created: now.Add(-3 * time.Hour), Activity: now.Add(-10 * time.Second), Attached: 1, Windows: 1})
+
+	b.AddSession("notes", tmux.PaneInfo{Index: 0, Width: 120, Height: 40})
+	b.SetPaneContent("notes", 0, `$ ls
+ideas.md  reading.md  todo.md
+$ `)
+	b.SetSessionInfo("notes", tmux.SessionInfo{Created: now.Add(-72 * time.Hour), Activity: now.Add(-5 * time.Hour), Windows: 1})
+
+	return b
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"warpp/internal/tmux"
+)
+
+// Border connection bits for a canvas cell
+const (
+	connUp = 1 << iota
+	connDown
+	connLeft
+	connRight
+)
+
+// boxRunes maps connection bits to box-drawing characters
+var boxRunes = map[int]rune{
+	connLeft | connRight:                     '─',
+	connLeft:                                 '─',
+	connRight:                                '─',
+	connUp | connDown:                        '│',
+	connUp:                                   '│',
+	connDown:                                 '│',
+	connDown | connRight:                     '╭',
+	connDown | connLeft:                      '╮',
+	connUp | connRight:                       '╰',
+	connUp | connLeft:                        '╯',
+	connUp | connDown | connRight:            '├',
+	connUp | connDown | connLeft:             '┤',
+	connLeft | connRight | connDown:          '┬',
+	connLeft | connRight | connUp:            '┴',
+	connUp | connDown | connLeft | connRight: '┼',
+}
+
+// Cell styles on a canvas
+const (
+	cellPlain = iota
+	cellBorder
+	cellLabel
+	cellMuted
+	cellActive
+)
+
+// canvas is a fixed-size character grid for drawing pane boxes. Shared
+// edges of neighbouring boxes merge into T and cross junctions.
+type canvas struct {
+	width, height int
+	conns         [][]int  // border connection bits per cell
+	runes         [][]rune // text written over the interior
+	styles        [][]int  // cell style per cell
+	spans         [][]span // pre-styled text per row, e.g. captured pane content
+}
+
+// span is pre-styled text placed on a canvas row, padded to width cells
+type span struct {
+	x, width int
+	text     string
+}
+
+func newCanvas(width, height int) *canvas {
+	c := &canvas{width: width, height: height}
+	c.conns = make([][]int, height)
+	c.runes = make([][]rune, height)
+	c.styles = make([][]int, height)
+	c.spans = make([][]span, height)
+	for y := 0; y < height; y++ {
+		c.conns[y] = make([]int, width)
+		c.runes[y] = make([]rune, width)
+		c.styles[y] = make([]int, width)
+	}
+	return c
+}
+
+// box draws a rectangle outline between two corners, inclusive
+func (c *canvas) box(x0, y0, x1, y1 int, style int) {
+	for x := x0; x <= x1; x++ {
+		bits := 0
+		if x > x0 {
+			bits |= connLeft
+		}
+		if x < x1 {
+			bits |= connRight
+		}
+		c.connect(x, y0, bits, style)
+		c.connect(x, y1, bits, style)
+	}
+	for y := y0; y <= y1; y++ {
+		bits := 0
+		if y > y0 {
+			bits |= connUp
+		}
+		if y < y1 {
+			bits |= connDown
+		}
+		c.connect(x0, y, bits, style)
+		c.connect(x1, y, bits, style)
+	}
+}
+
+func (c *canvas) connect(x, y, bits int, style int) {
+	if x < 0 || y < 0 || x >= c.width || y >= c.height {
+		return
+	}
+	c.conns[y][x] |= bits
+	// An active border wins over a plain one on shared edges
+	if c.styles[y][x] != cellActive {
+		c.styles[y][x] = style
+	}
+}
+
+// text writes s from (x, y), clipped to maxWidth cells
+func (c *canvas) text(x, y int, s string, maxWidth int, style int) {
+	if y < 0 || y >= c.height {
+		return
+	}
+	for _, r := range truncateRunes(s, maxWidth) {
+		if x >= 0 && x < c.width {
+			c.runes[y][x] = r
+			c.styles[y][x] = style
+		}
+		x++
+	}
+}
+
+// styled places ANSI-styled text at (x, y), clipped and padded to width cells
+func (c *canvas) styled(x, y int, text string, width int) {
+	if y < 0 || y >= c.height || x < 0 || width <= 0 || x+width > c.width {
+		return
+	}
+	c.spans[y] = append(c.spans[y], span{x: x, width: width, text: text})
+}
+
+// render returns the canvas as styled lines
+func (c *canvas) render(styles map[int]lipgloss.Style) string {
+	lines := make([]string, c.height)
+	for y := 0; y < c.height; y++ {
+		var line strings.Builder
+		var run strings.Builder
+		runStyle := -1
+		flush := func() {
+			if run.Len() == 0 {
+				return
+			}
+			if style, ok := styles[runStyle]; ok {
+				line.WriteString(style.Render(run.String()))
+			} else {
+				line.WriteString(run.String())
+			}
+			run.Reset()
+		}
+
+		spans := make(map[int]span, len(c.spans[y]))
+		for _, sp := range c.spans[y] {
+			spans[sp.x] = sp
+		}
+
+		for x := 0; x < c.width; x++ {
+			if sp, ok := spans[x]; ok {
+				flush()
+				runStyle = -1
+				text := truncateWithANSI(sp.text, sp.width)
+				line.WriteString(text + "\x1b[0m")
+				line.WriteString(strings.Repeat(" ", max(sp.width-lipgloss.Width(text), 0)))
+				x += sp.width - 1
+				continue
+			}
+
+			r := c.runes[y][x]
+			if r == 0 {
+				r = ' '
+				if bits := c.conns[y][x]; bits != 0 {
+					r = boxRunes[bits]
+				}
+			}
+			if c.styles[y][x] != runStyle {
+				flush()
+				runStyle = c.styles[y][x]
+			}
+			run.WriteRune(r)
+		}
+		flush()
+		lines[y] = line.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rectCorners scales a fractional pane rect onto a width x height grid.
+// Neighbouring rects land on the same border line.
+func rectCorners(r tmux.Rect, width, height int) (x0, y0, x1, y1 int) {
+	x0 = int(math.Round(r.X * float64(width-1)))
+	x1 = int(math.Round((r.X + r.W) * float64(width-1)))
+	y0 = int(math.Round(r.Y * float64(height-1)))
+	y1 = int(math.Round((r.Y + r.H) * float64(height-1)))
+	return
+}
+
+// renderLayoutDiagram draws each window of a layout as boxes for its panes,
+// labelled with the pane's startup command and working directory. Windows
+// that don't fit in height are summarised on the last line.
+func (m simpleModel) renderLayoutDiagram(spec *tmux.Spec, width, height int) string {
+	windows := spec.Windows
+	if len(windows) == 0 {
+		windows = []tmux.SpecWindow{{}}
+	}
+
+	// Each window needs a title line and a box of at least 4 lines
+	const minWindowHeight = 5
+	shown := len(windows)
+	if shown*minWindowHeight > height {
+		shown = max((height-1)/minWindowHeight, 1)
+	}
+	reserved := 0
+	if shown < len(windows) {
+		reserved = 1
+	}
+	perWindow := (height - reserved) / shown
+
+	styles := map[int]lipgloss.Style{
+		cellBorder: lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Border)),
+		cellLabel:  m.styles.Normal,
+		cellMuted:  m.styles.Muted,
+	}
+
+	var parts []string
+	for wi, win := range windows[:shown] {
+		title := fmt.Sprintf("%d", wi+1)
+		if win.Name != "" {
+			title += ": " + win.Name
+		}
+		parts = append(parts, m.styles.Muted.Render(truncateRunes(title, width)))
+
+		boxHeight := perWindow - 1
+		c := newCanvas(width, boxHeight)
+		for pi, r := range win.Geometry() {
+			x0, y0, x1, y1 := rectCorners(r, width, boxHeight)
+			c.box(x0, y0, x1, y1, cellBorder)
+
+			innerWidth := x1 - x0 - 1
+			label := "shell"
+			var pane tmux.SpecPane
+			if pi < len(win.Panes) {
+				pane = win.Panes[pi]
+			}
+			if commands := pane.StartupCommands(); len(commands) > 0 {
+				label = strings.Join(commands, "; ")
+			}
+			if y0+1 < y1 {
+				c.text(x0+1, y0+1, label, innerWidth, cellLabel)
+			}
+			if y0+2 < y1 {
+				c.text(x0+1, y0+2, shortenHome(spec.PaneRoot("", wi, pi)), innerWidth, cellMuted)
+			}
+		}
+		parts = append(parts, c.render(styles))
+	}
+
+	if shown < len(windows) {
+		parts = append(parts, m.styles.Muted.Render(fmt.Sprintf("+%d more windows", len(windows)-shown)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// renderLiveLayout draws a running window's panes at their real positions,
+// scaled to width x height, with each pane's captured content inside its box
+// and the active pane's border highlighted. It returns "" if the panes carry
+// no geometry.
+func (m simpleModel) renderLiveLayout(panes []tmux.PaneInfo, width, height int) string {
+	rects := tmux.PaneGeometry(panes)
+	if rects == nil || width < 3 || height < 3 {
+		return ""
+	}
+
+	c := newCanvas(width, height)
+	// Draw the active pane last so its border wins on shared edges
+	order := make([]int, 0, len(panes))
+	for i, pane := range panes {
+		if !pane.Active {
+			order = append(order, i)
+		}
+	}
+	for i, pane := range panes {
+		if pane.Active {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		x0, y0, x1, y1 := rectCorners(rects[i], width, height)
+		style := cellBorder
+		if panes[i].Active {
+			style = cellActive
+		}
+		c.box(x0, y0, x1, y1, style)
+
+		// Show the bottom of the pane's content, where the prompt usually is
+		innerWidth, innerHeight := x1-x0-1, y1-y0-1
+		if innerWidth <= 0 || innerHeight <= 0 {
+			continue
+		}
+		lines := contentLines(panes[i].Content)
+		if len(lines) > innerHeight {
+			lines = lines[len(lines)-innerHeight:]
+		}
+		for j, line := range lines {
+			c.styled(x0+1, y0+1+j, line, innerWidth)
+		}
+	}
+
+	return c.render(map[int]lipgloss.Style{
+		cellBorder: lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Border)),
+		cellActive: lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Primary)),
+	})
+}
+
+// contentLines splits captured pane content into lines, dropping the blank
+// lines below the last output
+func contentLines(content string) []string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(ansiRegex.ReplaceAllString(lines[len(lines)-1], "")) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// truncateRunes clips s to maxWidth runes, ending with … when clipped
+func truncateRunes(s string, maxWidth int) string {
+	runes := []rune(s)
+	if maxWidth <= 0 {
+		return ""
+	}
+	if len(runes) <= maxWidth {
+		return s
+	}
+	return string(runes[:maxWidth-1]) + "…"
+}
+
+// shortenHome replaces the home directory prefix of a path with ~
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+"/") {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"unicode"
+)
+
+// Fuzzy match scoring
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 8
+	bonusBoundary    = 8 // match at the start of a word
+	penaltyGap       = 1 // per skipped rune inside the match
+)
+
+// fuzzyMatch reports whether the runes of pattern appear in text in order,
+// ignoring case. Among the possible matches it picks the shortest one ending
+// at the first place the whole pattern fits, like fzf. It returns the rune
+// positions matched in text and a score that favours consecutive runes and
+// word starts.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(toLowerString(pattern))
+	t := []rune(text)
+	lower := []rune(toLowerString(text))
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	// Forward: find where the first full match ends
+	pi, end := 0, -1
+	for i, r := range lower {
+		if r == p[pi] {
+			pi++
+			if pi == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward: find the latest start that still matches up to end
+	pi, start := len(p)-1, end
+	for i := end; i >= 0; i-- {
+		if lower[i] == p[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	// Forward again from start to collect positions and score them
+	positions := make([]int, 0, len(p))
+	score := 0
+	pi = 0
+	for i := start; i <= end && pi < len(p); i++ {
+		if lower[i] != p[pi] {
+			score -= penaltyGap
+			continue
+		}
+		score += scoreMatch
+		if len(positions) > 0 && positions[len(positions)-1] == i-1 {
+			score += bonusConsecutive
+		}
+		if isWordStart(t, i) {
+			score += bonusBoundary
+		}
+		positions = append(positions, i)
+		pi++
+	}
+	return score, positions, true
+}
+
+// isWordStart reports whether the rune at i begins a word: the start of the
+// text, after a separator, or a lower-to-upper case change
+func isWordStart(t []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := t[i-1], t[i]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// toLowerString lowercases s rune by rune, so rune positions stay aligned
+// with s (strings.ToLower may change the rune count)
+func toLowerString(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"warpp/internal/tmux"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+		positions     []int
+	}{
+		{pattern: "", text: "web", ok: true},
+		{pattern: "web", text: "web", ok: true, positions: []int{0, 1, 2}},
+		{pattern: "WEB", text: "my-Web", ok: true, positions: []int{3, 4, 5}},
+		{pattern: "wa", text: "warpp", ok: true, positions: []int{0, 1}},
+		{pattern: "wp", text: "warpp", ok: true, positions: []int{0, 3}},
+		{pattern: "pw", text: "warpp", ok: false},
+		{pattern: "api", text: "web", ok: false},
+		// Shortest match ending at the first full match
+		{pattern: "ab", text: "a_a_b", ok: true, positions: []int{2, 4}},
+		// Positions count runes, not bytes
+		{pattern: "ké", text: "café-kéy", ok: true, positions: []int{5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.text, func(t *testing.T) {
+			_, positions, ok := fuzzyMatch(tt.pattern, tt.text)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !reflect.DeepEqual(positions, tt.positions) {
+				t.Errorf("positions = %v, want %v", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// Each pattern should score higher against better than worse
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{pattern: "api", better: "api", worse: "a-p-i"},
+		{pattern: "ws", better: "web-server", worse: "towns"},
+		{pattern: "gs", better: "goServer", worse: "gossip"},
+		{pattern: "doc", better: "docs", worse: "dxoxc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			better, _, ok1 := fuzzyMatch(tt.pattern, tt.better)
+			worse, _, ok2 := fuzzyMatch(tt.pattern, tt.worse)
+			if !ok1 || !ok2 {
+				t.Fatalf("no match: %v %v", ok1, ok2)
+			}
+			if better <= worse {
+				t.Errorf("%q scored %d, not above %q at %d", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
+
+func TestRowsQuery(t *testing.T) {
+	m := simpleModel{sessions: []tmux.Session{
+		{Name: "api", IsRunning: true, Description: "web backend"},
+		{Name: "notes", IsRunning: true},
+		{Name: "web", IsRunning: true},
+		{Name: "webapp", IsLayout: true},
+	}}
+
+	tests := []struct {
+		query string
+		want  []string
+		best  string
+	}{
+		{query: "web", want: []string{"web", "api", "webapp"}, best: "web"},
+		{query: "nts", want: []string{"notes"}, best: "notes"},
+		{query: "bapp", want: []string{"webapp"}, best: "webapp"},
+		{query: "zzz", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			m.query = tt.query
+			rows := m.rows()
+			var got []string
+			for _, row := range rows {
+				got = append(got, row.session.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("rows = %v, want %v", got, tt.want)
+			}
+			if len(rows) > 0 && rows[bestMatch(rows)].session.Name != tt.best {
+				t.Errorf("best match = %q, want %q", rows[bestMatch(rows)].session.Name, tt.best)
+			}
+		})
+	}
+}
+
+func TestMatchSession(t *testing.T) {
+	session := tmux.Session{
+		Name:        "web",
+		Description: "frontend",
+		ProjectRoot: "/home/test/code/shop",
+		ProjectType: "node",
+	}
+	tests := []struct {
+		query   string
+		ok      bool
+		matched []int
+	}{
+		{query: "wb", ok: true, matched: []int{0, 2}},
+		{query: "front", ok: true},
+		{query: "shop", ok: true},
+		{query: "node", ok: true},
+		{query: "rails", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			row, ok := matchSession(session, tt.query)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !reflect.DeepEqual(row.matched, tt.matched) {
+				t.Errorf("matched = %v, want %v", row.matched, tt.matched)
+			}
+		})
+	}
+
+	// The same match ranks higher in the name than in another field
+	byName, _ := matchSession(tmux.Session{Name: "api"}, "api")
+	byDescription, _ := matchSession(tmux.Session{Name: "x", Description: "api"}, "api")
+	if byName.score <= byDescription.score {
+		t.Errorf("name match scored %d, not above description match at %d", byName.score, byDescription.score)
+	}
+}
+
+func TestRenderMatches(t *testing.T) {
+	base := lipgloss.NewStyle()
+	highlight := lipgloss.NewStyle().Transform(func(s string) string { return "[" + s + "]" })
+	tests := []struct {
+		text      string
+		positions []int
+		want      string
+	}{
+		{text: "web", want: "web"},
+		{text: "webapp", positions: []int{0, 1, 4}, want: "[we]ba[p]p"},
+		{text: "café", positions: []int{3}, want: "caf[é]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := renderMatches(tt.text, tt.positions, base, highlight); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBeforeFirstLoad(t *testing.T) {
+	// warpp pick --query sets the query before any session is loaded. The
+	// layout matches best but is listed after running sessions.
+	m := simpleModel{
+		searching: true,
+		query:     "web",
+		previews:  make(map[string]sessionPreview),
+		expanded:  make(map[string]bool),
+		windows:   make(map[string][]tmux.WindowInfo),
+	}
+	updated, _ := m.Update([]tmux.Session{
+		{Name: "api", IsRunning: true, Description: "web backend"},
+		{Name: "web", IsLayout: true},
+	})
+	m = updated.(simpleModel)
+	if row, ok := m.selectedRow(); !ok || row.session.Name != "web" {
+		t.Errorf("selected %q, want web", row.session.Name)
+	}
+
+	// Later loads keep the selection
+	m.cursor = 0
+	updated, _ = m.Update([]tmux.Session{
+		{Name: "api", IsRunning: true, Description: "web backend"},
+		{Name: "web", IsLayout: true},
+	})
+	if row, _ := updated.(simpleModel).selectedRow(); row.session.Name != "api" {
+		t.Errorf("selected %q after reload, want api", row.session.Name)
+	}
+}
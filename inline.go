@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// TUI display modes
+const (
+	modeFullscreen = "fullscreen" // header and panels on the alternate screen
+	modeInline     = "inline"     // a compact list below the prompt
+	modePopup      = "popup"      // panels filling a tmux popup
+)
+
+// inlinePreviewLine returns one line describing the selected row: the last
+// line of output of its pane for running sessions, and the description of
+// layouts
+func (m simpleModel) inlinePreviewLine() string {
+	row, ok := m.selectedRow()
+	if !ok {
+		return ""
+	}
+	if !row.session.IsRunning {
+		return row.session.Description
+	}
+	panes := m.previews[row.session.Name].panes
+	if len(panes) == 0 {
+		return ""
+	}
+	// The picked pane, else the window's active pane
+	pane := panes[0]
+	for _, p := range panes {
+		if (row.pane != nil && p.ID == row.pane.ID) || (row.pane == nil && p.Active) {
+			pane = p
+			break
+		}
+	}
+	lines := strings.Split(strings.TrimRight(pane.Content, "\n "), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// inlineStatus returns the last line of the inline view: an error, a prompt
+// or the keybinding hints
+func (m simpleModel) inlineStatus(width int) string {
+	switch {
+	case m.errorMessage != "":
+		return m.styles.Error.Render(truncateRunes("✗ "+m.errorMessage, width))
+	case m.worktreeInputStep == 1:
+		return m.styles.Title.Render("Session name: ") + m.styles.Normal.Render(m.worktreeSessionName+"█") +
+			m.styles.Muted.Render("  Enter continue · Esc cancel")
+	case m.worktreeInputStep == 2:
+		return m.styles.Title.Render("Branch name: ") + m.styles.Normal.Render(m.worktreeBranchName+"█") +
+			m.styles.Muted.Render("  Enter create · Esc back")
+	case m.confirmingKill:
+		if row, ok := m.selectedRow(); ok {
+			return m.styles.Warning.Render(fmt.Sprintf("Kill session '%s'? ", row.session.Name)) +
+				m.styles.Muted.Render("y/Enter confirm · n/Esc cancel")
+		}
+	}
+	keys := strings.ReplaceAll(m.footerKeys(), "  •  ", " · ")
+	return m.styles.Muted.Render(truncateWithANSI(keys, width))
+}
+
+// inlineView renders inline mode: a search line, a fixed number of list
+// rows, an optional preview line and a status line. The height never
+// changes so the prompt above stays put, and nothing is left behind on exit.
+func (m simpleModel) inlineView() string {
+	if m.quitting {
+		return ""
+	}
+	width := 80
+	if m.width > 0 {
+		width = m.width - 1
+	}
+	listWidth := min(width, 60)
+
+	height := m.inlineHeight
+	var lines []string
+	if m.searching || m.query != "" {
+		query := m.query
+		if m.searching {
+			query += "▏"
+		}
+		lines = append(lines, m.styles.Title.Render("/ ")+m.styles.Normal.Render(truncateRunes(query, width-3)))
+		height--
+	}
+
+	rows := m.rows()
+	var items []string
+	switch {
+	case len(m.sessions) == 0:
+		items = []string{m.styles.Muted.Render("   Loading sessions...")}
+	case len(rows) == 0:
+		items = []string{m.styles.Muted.Render("   No matches")}
+	}
+	maxNameWidth := 0
+	for _, session := range m.sessions {
+		maxNameWidth = max(maxNameWidth, runewidth.StringWidth(session.Name))
+	}
+	for i, row := range rows {
+		items = append(items, m.renderRow(row, i == m.cursor, listWidth, maxNameWidth))
+	}
+
+	// Scroll the list to keep the cursor in view, and pad it to its height
+	if len(items) > height {
+		start := min(max(m.cursor-height/2, 0), len(items)-height)
+		items = items[start : start+height]
+	}
+	for len(items) < height {
+		items = append(items, "")
+	}
+	lines = append(lines, items...)
+
+	if m.inlinePreview {
+		preview := truncateWithANSI(m.inlinePreviewLine(), width-4)
+		lines = append(lines, m.styles.Muted.Render("   ╰ ")+preview)
+	}
+	lines = append(lines, m.inlineStatus(width))
+
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
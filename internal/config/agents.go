@@ -0,0 +1,100 @@
+package config
+
+// Agent describes how to detect a coding agent running in a pane and how to
+// show it in the list. Patterns are Go regular expressions.
+type Agent struct {
+	Name      string   `json:"name"`
+	Process   string   `json:"process,omitempty"`   // matched against the command line of each process in a pane
+	Executing []string `json:"executing,omitempty"` // matched against the pane's visible text
+	Idle      []string `json:"idle,omitempty"`
+	Waiting   []string `json:"waiting,omitempty"` // the agent is asking for input or approval
+	Icon      string   `json:"icon,omitempty"`
+	Color     string   `json:"color,omitempty"`
+	Resume    string   `json:"resume,omitempty"` // offered by warpp restore in panes that ran the agent
+	Disabled  bool     `json:"disabled,omitempty"`
+}
+
+// choiceMenu matches a numbered choice menu with a selection cursor, e.g.
+// "❯ 1. Yes", as agents show for permission prompts and questions
+const choiceMenu = `(?m)^[\s│|]*[❯›▶]\s*\d+\.\s`
+
+// DefaultAgents returns the built-in agent detectors
+func DefaultAgents() []Agent {
+	return []Agent{
+		{
+			Name:      "claude",
+			Process:   `(^|/)claude(\s|$)`,
+			Executing: []string{`\(esc to interrupt`},
+			Waiting:   []string{`Do you want to (proceed|make this edit|create|allow|run)`, `Would you like to`, `requires approval`, choiceMenu},
+			Icon:      "●",
+			Color:     "#FF8C00",
+			Resume:    "claude --resume",
+		},
+		{
+			Name:      "codex",
+			Process:   `(^|/)codex(\s|$)`,
+			Executing: []string{`esc to interrupt`},
+			Waiting:   []string{`Allow command\?`, `\[y/n\]`, `Would you like to`, choiceMenu},
+			Icon:      "◆",
+			Color:     "#10A37F",
+			Resume:    "codex resume",
+		},
+		{
+			Name:    "aider",
+			Process: `(^|/)aider(\s|$)`,
+			Waiting: []string{`\(Y\)es/\(N\)o`, `\[Yes\]:`},
+			Icon:    "▲",
+			Color:   "#14B014",
+		},
+		{
+			Name:      "gemini",
+			Process:   `(^|/)gemini(\s|$)`,
+			Executing: []string{`esc to cancel`},
+			Waiting:   []string{`Allow execution`, `Apply this change\?`, `Do you want to proceed`, choiceMenu},
+			Icon:      "✦",
+			Color:     "#4285F4",
+		},
+	}
+}
+
+// mergeAgents applies configured agents over the defaults: an entry with a
+// default's name replaces its non-empty fields, and other entries are added
+// after the defaults
+func mergeAgents(defaults, configured []Agent) []Agent {
+	merged := append([]Agent(nil), defaults...)
+	for _, agent := range configured {
+		found := false
+		for i := range merged {
+			if merged[i].Name != agent.Name {
+				continue
+			}
+			found = true
+			if agent.Process != "" {
+				merged[i].Process = agent.Process
+			}
+			if agent.Executing != nil {
+				merged[i].Executing = agent.Executing
+			}
+			if agent.Idle != nil {
+				merged[i].Idle = agent.Idle
+			}
+			if agent.Waiting != nil {
+				merged[i].Waiting = agent.Waiting
+			}
+			if agent.Icon != "" {
+				merged[i].Icon = agent.Icon
+			}
+			if agent.Color != "" {
+				merged[i].Color = agent.Color
+			}
+			if agent.Resume != "" {
+				merged[i].Resume = agent.Resume
+			}
+			merged[i].Disabled = agent.Disabled
+		}
+		if !found {
+			merged = append(merged, agent)
+		}
+	}
+	return merged
+}
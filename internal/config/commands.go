@@ -42,5 +42,28 @@ func ShowConfig() error {
 	fmt.Printf("Config file: %s\n", configPath)
 	fmt.Printf("Theme: %s\n", config.Theme)
 	fmt.Printf("ASCII Art: %s\n", config.ASCIIArt)
+	fmt.Printf("Sort Mode: %s\n", config.SortMode)
+	fmt.Printf("Show Recent: %t\n", config.ShowRecent)
+	fmt.Printf("Waiting First: %t\n", config.WaitingFirst)
+	fmt.Printf("Inline: %t (%d rows, preview %t)\n", config.Inline, config.InlineHeight, config.InlinePreview)
+	autosave := "off"
+	if config.Snapshot.Autosave > 0 {
+		autosave = fmt.Sprintf("every %d min", config.Snapshot.Autosave)
+	}
+	fmt.Printf("Snapshots: keep %d, autosave %s, %d scrollback lines, %d killed sessions in the trash\n", config.Snapshot.Keep, autosave, config.Snapshot.Scrollback, config.Snapshot.Trash)
+	for _, agent := range config.Agents {
+		status := ""
+		if agent.Disabled {
+			status = " (disabled)"
+		}
+		fmt.Printf("Agent: %s %s%s\n", agent.Icon, agent.Name, status)
+	}
+	for _, rule := range config.Rules {
+		from := rule.From
+		if from == "" {
+			from = "*"
+		}
+		fmt.Printf("Rule: %s -> %s\n", from, rule.To)
+	}
 	return nil
 }
@@ -7,15 +7,42 @@ import (
 )
 
 type Config struct {
-	Theme    string `json:"theme"`
-	ASCIIArt string `json:"ascii_art"`
+	Theme         string   `json:"theme"`
+	ASCIIArt      string   `json:"ascii_art"`
+	SortMode      string   `json:"sort_mode"`        // SortByName, SortByFrecency or SortByActivity
+	ShowRecent    bool     `json:"show_recent"`      // list recently used sessions in their own group first
+	WaitingFirst  bool     `json:"waiting_first"`    // list sessions with an agent waiting for input first
+	Inline        bool     `json:"inline"`           // draw a compact list below the prompt instead of full screen
+	InlineHeight  int      `json:"inline_height"`    // list rows in inline mode
+	InlinePreview bool     `json:"inline_preview"`   // one line of the selected session under the inline list
+	Agents        []Agent  `json:"agents,omitempty"` // merged over DefaultAgents by name
+	Rules         []Rule   `json:"rules,omitempty"`  // actions on agent state changes
+	Snapshot      Snapshot `json:"snapshot"`
 }
 
+// Sort modes for the session list
+const (
+	SortByName     = "name"
+	SortByFrecency = "frecency"
+	SortByActivity = "activity"
+)
+
+// SortModes lists the sort modes in the order the TUI cycles through them
+var SortModes = []string{SortByName, SortByFrecency, SortByActivity}
+
+// DefaultInlineHeight is the number of list rows in inline mode
+const DefaultInlineHeight = 10
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
-		Theme:    "default",
-		ASCIIArt: "fire",
+		Theme:         "default",
+		ASCIIArt:      "fire",
+		SortMode:      SortByName,
+		InlineHeight:  DefaultInlineHeight,
+		InlinePreview: true,
+		Snapshot:      Snapshot{Keep: DefaultSnapshotKeep, Trash: DefaultTrash},
+		Agents:        DefaultAgents(),
 	}
 }
 
@@ -36,10 +63,28 @@ func LoadConfig() (Config, error) {
 		return DefaultConfig(), err
 	}
 
-	var config Config
+	// Fields missing from the file keep their defaults; configured agents
+	// are merged over the built-in ones
+	config := DefaultConfig()
+	config.Agents = nil
 	if err := json.Unmarshal(data, &config); err != nil {
 		return DefaultConfig(), err
 	}
+	config.Agents = mergeAgents(DefaultAgents(), config.Agents)
+	switch config.SortMode {
+	case SortByName, SortByFrecency, SortByActivity:
+	default:
+		config.SortMode = SortByName
+	}
+	if config.InlineHeight < 1 {
+		config.InlineHeight = DefaultInlineHeight
+	}
+	if config.Snapshot.Keep < 1 {
+		config.Snapshot.Keep = DefaultSnapshotKeep
+	}
+	if config.Snapshot.Trash < 1 {
+		config.Snapshot.Trash = DefaultTrash
+	}
 
 	return config, nil
 }
@@ -0,0 +1,15 @@
+package config
+
+// Rule reacts to a coding agent changing state, e.g. from executing to
+// idle when it finishes. Command and Message are Go templates over the
+// transition: {{.Session}}, {{.Agent}}, {{.Pane}}, {{.PaneID}}, {{.From}},
+// {{.To}} and {{.Elapsed}}, the time spent in the previous state.
+type Rule struct {
+	Agent   string `json:"agent,omitempty"`   // agent name; any when empty
+	Session string `json:"session,omitempty"` // session name glob; any when empty
+	From    string `json:"from,omitempty"`    // previous state; any when empty
+	To      string `json:"to"`                // new state: waiting, executing or idle
+	Command string `json:"command,omitempty"` // run with sh -c
+	Message string `json:"message,omitempty"` // shown on attached tmux clients
+	Bell    bool   `json:"bell,omitempty"`    // ring the bell on attached tmux clients
+}
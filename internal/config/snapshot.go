@@ -0,0 +1,15 @@
+package config
+
+// Defaults for the number of snapshots and killed sessions kept
+const (
+	DefaultSnapshotKeep = 10
+	DefaultTrash        = 10
+)
+
+// Snapshot configures warpp snapshot and the daemon's autosave
+type Snapshot struct {
+	Autosave   int `json:"autosave,omitempty"`   // minutes between snapshots taken by warpp daemon; 0 disables
+	Keep       int `json:"keep,omitempty"`       // snapshots kept; older ones are deleted
+	Scrollback int `json:"scrollback,omitempty"` // history lines saved per pane; 0 saves none
+	Trash      int `json:"trash,omitempty"`      // killed sessions kept so kills can be undone
+}
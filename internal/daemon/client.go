@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"warpp/internal/tmux"
+)
+
+// ErrUnavailable means no daemon is listening; callers fall back to
+// talking to tmux directly
+var ErrUnavailable = errors.New("warpp daemon is not running")
+
+// dialTimeout bounds connecting to the socket; a live daemon answers at once
+const dialTimeout = 200 * time.Millisecond
+
+// Calls whose context has no deadline get one of these. A daemon that
+// doesn't answer in time counts as unavailable, so callers fall back to tmux.
+const (
+	callTimeout   = 500 * time.Millisecond
+	actionTimeout = 5 * time.Second // kill and launch run tmux themselves
+)
+
+// Client calls the daemon's API. Every call uses its own connection, so a
+// Client is safe for concurrent use and works across daemon restarts. A nil
+// Client never reaches a daemon.
+type Client struct {
+	path string
+}
+
+// NewClient returns a client for the daemon at SocketPath
+func NewClient() *Client {
+	return &Client{path: SocketPath()}
+}
+
+// dial connects to the daemon, reporting ErrUnavailable when it isn't
+// running
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c == nil {
+		return nil, ErrUnavailable
+	}
+	if err := checkSocketDir(filepath.Dir(c.path)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// call sends one request and decodes its result into result, if not nil.
+// Unless ctx has a deadline the call gives up after timeout. An error reply
+// is returned as an *RPCError carrying tmux's error kind, so callers check
+// it with errors.Is as they would a tmux error.
+func (c *Client) call(ctx context.Context, timeout time.Duration, method string, params, result any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = writeRequest(conn, method, params)
+	var resp response
+	if err == nil {
+		err = json.NewDecoder(bufio.NewReader(conn)).Decode(&resp)
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: no answer to %s in time", ErrUnavailable, method)
+	}
+	if err != nil {
+		return fmt.Errorf("daemon %s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+func writeRequest(conn net.Conn, method string, params any) error {
+	id := 1
+	req := request{JSONRPC: "2.0", ID: &id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = data
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
+
+// List returns running sessions and layouts as the daemon last saw them
+func (c *Client) List(ctx context.Context) ([]tmux.Session, error) {
+	var sessions []tmux.Session
+	err := c.call(ctx, callTimeout, MethodList, nil, &sessions)
+	return sessions, err
+}
+
+// Kill kills a running session
+func (c *Client) Kill(ctx context.Context, name string) error {
+	return c.call(ctx, actionTimeout, MethodKill, NameParams{Name: name}, nil)
+}
+
+// Launch starts a layout unless its session is running, and records the
+// launch
+func (c *Client) Launch(ctx context.Context, name string) error {
+	return c.call(ctx, actionTimeout, MethodLaunch, NameParams{Name: name}, nil)
+}
+
+// Shutdown stops the daemon
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.call(ctx, callTimeout, MethodShutdown, nil, nil)
+}
+
+// Subscribe streams the daemon's events on the returned channel, starting
+// with the current sessions, until ctx is done or the daemon goes away,
+// when the channel is closed
+func (c *Client) Subscribe(ctx context.Context) (<-chan Event, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for the first event as for any call, then stream without a deadline
+	conn.SetDeadline(time.Now().Add(callTimeout))
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	err = writeRequest(conn, MethodSubscribe, nil)
+	var first Event
+	if err == nil {
+		first, err = readEvent(decoder)
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = fmt.Errorf("%w: no answer to %s in time", ErrUnavailable, MethodSubscribe)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	events := make(chan Event, 16)
+	events <- first
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	go func() {
+		defer close(events)
+		defer close(stop)
+		defer conn.Close()
+		for {
+			event, err := readEvent(decoder)
+			if err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// readEvent reads the next event notification, skipping anything else
+func readEvent(decoder *json.Decoder) (Event, error) {
+	for {
+		var msg notification
+		if err := decoder.Decode(&msg); err != nil {
+			return Event{}, err
+		}
+		if msg.Error != nil {
+			return Event{}, msg.Error
+		}
+		if msg.Method != methodEvent {
+			continue
+		}
+		var event Event
+		err := json.Unmarshal(msg.Params, &event)
+		return event, err
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"warpp/internal/rules"
+	"warpp/internal/tmux"
+)
+
+// The daemon speaks JSON-RPC 2.0 over a Unix socket, one JSON object per
+// line. Methods:
+//
+//	list       running sessions and layouts, with agent states
+//	history    the launch history
+//	kill       {"name": ...} kills a running session
+//	launch     {"name": ...} starts a layout unless it's running, and
+//	           records the launch; attaching is up to the client
+//	subscribe  streams "event" notifications on the connection, starting
+//	           with the current sessions
+//	shutdown   stops the daemon
+const (
+	MethodList      = "list"
+	MethodHistory   = "history"
+	MethodKill      = "kill"
+	MethodLaunch    = "launch"
+	MethodSubscribe = "subscribe"
+	MethodShutdown  = "shutdown"
+
+	// methodEvent is the notification carrying an Event to subscribers
+	methodEvent = "event"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeServerError    = -32000
+)
+
+// Event types
+const (
+	EventSessions   = "sessions"   // the session list changed
+	EventTransition = "transition" // an agent changed state
+)
+
+// Event is pushed to subscribers
+type Event struct {
+	Type       string            `json:"type"`
+	Sessions   []tmux.Session    `json:"sessions,omitempty"`
+	Transition *rules.Transition `json:"transition,omitempty"`
+}
+
+// NameParams are the params of kill and launch
+type NameParams struct {
+	Name string `json:"name"`
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"` // nil for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"` // set on notifications
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// notification is how a client reads a message that may be an event or an
+// error reply
+type notification struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Error  *RPCError       `json:"error"`
+}
+
+// RPCError is an error returned by the daemon. Data names the kind of a
+// tmux error, so errors.Is matches the same kinds as calling tmux directly.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"` // a key of errorKinds
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the tmux error kind named by Data, if any
+func (e *RPCError) Unwrap() error {
+	return errorKinds[e.Data]
+}
+
+// errorKinds names the tmux error kinds for RPCError.Data
+var errorKinds = map[string]error{
+	"not_installed":     tmux.ErrNotInstalled,
+	"no_server":         tmux.ErrNoServer,
+	"session_not_found": tmux.ErrSessionNotFound,
+	"tmuxifier_failed":  tmux.ErrTmuxifierFailed,
+	"timed_out":         tmux.ErrCommandTimedOut,
+}
+
+// SocketPath returns the daemon's socket: in $XDG_RUNTIME_DIR when set, and
+// otherwise in a per-user directory under the system temp dir. Listen and
+// the client check the directory is the user's own.
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "warpp", "daemon.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("warpp-%d", os.Getuid()), "daemon.sock")
+}
+
+// checkSocketDir makes sure dir is a directory, not a symlink, owned by the
+// current user and closed to everyone else. In a shared temp dir another
+// user could otherwise create it first and plant or read the socket.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	switch {
+	case !info.IsDir():
+		return fmt.Errorf("%s is not a directory", dir)
+	case !ok || int(stat.Uid) != os.Getuid():
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	case info.Mode().Perm() != 0700:
+		return fmt.Errorf("%s has mode %04o, want 0700", dir, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"warpp/internal/tmux"
+)
+
+// startServer serves a fake tmux server with the sessions api and web on a
+// socket in a temporary directory, and waits for its first poll
+func startServer(t *testing.T) (*Client, *tmux.FakeBackend) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	b := tmux.NewFakeBackend()
+	b.AddSession("api")
+	b.AddSession("web")
+
+	path := filepath.Join(t.TempDir(), "warpp", "daemon.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(b, nil).Serve(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	client := &Client{path: path}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if sessions, err := client.List(context.Background()); err == nil && len(sessions) > 0 {
+			return client, b
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("daemon never listed the sessions")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		result  string // JSON result, when no error is expected
+		code    int    // JSON-RPC error code
+	}{
+		{name: "list", request: `{"jsonrpc":"2.0","id":1,"method":"list"}`, result: "api,web"},
+		{name: "kill", request: `{"jsonrpc":"2.0","id":2,"method":"kill","params":{"name":"api"}}`, result: "true"},
+		{name: "kill missing session", request: `{"jsonrpc":"2.0","id":3,"method":"kill","params":{"name":"docs"}}`, code: codeServerError},
+		{name: "kill without a name", request: `{"jsonrpc":"2.0","id":4,"method":"kill","params":{}}`, code: codeInvalidParams},
+		{name: "launch without params", request: `{"jsonrpc":"2.0","id":5,"method":"launch"}`, code: codeInvalidParams},
+		{name: "launch unknown layout", request: `{"jsonrpc":"2.0","id":6,"method":"launch","params":{"name":"docs"}}`, code: codeServerError},
+		{name: "unknown method", request: `{"jsonrpc":"2.0","id":7,"method":"restart"}`, code: codeMethodNotFound},
+		{name: "not JSON", request: `list`, code: codeParseError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := startServer(t)
+			conn, err := client.dial(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			// A notification gets no response, so the next line answers the request
+			conn.Write([]byte(`{"jsonrpc":"2.0","method":"list"}` + "\n" + tt.request + "\n"))
+			var resp response
+			if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.code != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.code {
+					t.Fatalf("error = %+v, want code %d", resp.Error, tt.code)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("error = %v", resp.Error)
+			}
+			var req request
+			json.Unmarshal([]byte(tt.request), &req)
+			if resp.ID == nil || *resp.ID != *req.ID {
+				t.Errorf("id = %v, want %d", resp.ID, *req.ID)
+			}
+			got := string(resp.Result)
+			var sessions []tmux.Session
+			if json.Unmarshal(resp.Result, &sessions) == nil {
+				var names []string
+				for _, s := range sessions {
+					names = append(names, s.Name)
+				}
+				got = strings.Join(names, ",")
+			}
+			if got != tt.result {
+				t.Errorf("result = %s, want %s", got, tt.result)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	client, b := startServer(t)
+	ctx := context.Background()
+
+	if err := client.Kill(ctx, "api"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, _ := b.ListSessions(ctx); len(sessions) != 1 || sessions[0].Name != "web" {
+		t.Errorf("tmux sessions after kill: %+v", sessions)
+	}
+	// The daemon refreshes its list after a kill
+	sessions, err := client.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Name != "web" {
+		t.Errorf("listed after kill: %+v", sessions)
+	}
+
+	// Errors keep their tmux kind across the socket
+	var rpcErr *RPCError
+	if err := client.Kill(ctx, "api"); !errors.As(err, &rpcErr) || !errors.Is(err, tmux.ErrSessionNotFound) {
+		t.Errorf("killing a missing session: %v", err)
+	}
+	if err := client.Launch(ctx, "docs"); !errors.As(err, &rpcErr) || errors.Is(err, tmux.ErrSessionNotFound) {
+		t.Errorf("launching a missing layout: %v", err)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	client, _ := startServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() Event {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("events closed")
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+		return Event{}
+	}
+	names := func(event Event) string {
+		var names []string
+		for _, s := range event.Sessions {
+			names = append(names, s.Name)
+		}
+		return event.Type + ":" + strings.Join(names, ",")
+	}
+
+	if got := names(next()); got != "sessions:api,web" {
+		t.Errorf("first event %s, want the current sessions", got)
+	}
+	if err := client.Kill(context.Background(), "api"); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(next()); got != "sessions:web" {
+		t.Errorf("event after kill %s", got)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestRPCErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		data string
+	}{
+		{name: "session not found", err: &tmux.CommandError{Name: "tmux", Kind: tmux.ErrSessionNotFound}, data: "session_not_found"},
+		{name: "no server", err: fmt.Errorf("listing: %w", &tmux.CommandError{Name: "tmux", Kind: tmux.ErrNoServer}), data: "no_server"},
+		{name: "unclassified", err: errors.New("disk full")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(serverError(tt.err))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got RPCError
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Data != tt.data || got.Message != tt.err.Error() {
+				t.Errorf("got %+v", got)
+			}
+			if kind := errorKinds[tt.data]; kind != nil && !errors.Is(&got, kind) {
+				t.Errorf("%v doesn't match %v", &got, kind)
+			}
+		})
+	}
+}
+
+func TestClientUnavailable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "warpp")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// A daemon that accepts connections but never answers
+	hung := filepath.Join(dir, "hung.sock")
+	l, err := net.Listen("unix", hung)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name   string
+		client *Client
+	}{
+		{name: "nil client", client: nil},
+		{name: "no socket", client: &Client{path: filepath.Join(dir, "daemon.sock")}},
+		{name: "no directory", client: &Client{path: filepath.Join(dir, "missing", "daemon.sock")}},
+		{name: "no answer", client: &Client{path: hung}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := tt.client.List(context.Background())
+			if !errors.Is(err, ErrUnavailable) {
+				t.Errorf("err = %v, want ErrUnavailable", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*callTimeout {
+				t.Errorf("took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestCheckSocketDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(dir string) error
+		wantErr string
+	}{
+		{name: "private directory", setup: func(dir string) error { return os.Mkdir(dir, 0700) }},
+		{
+			name:    "readable by others",
+			setup:   func(dir string) error { os.Mkdir(dir, 0700); return os.Chmod(dir, 0755) },
+			wantErr: "has mode 0755, want 0700",
+		},
+		{
+			name: "symlink to a private directory",
+			setup: func(dir string) error {
+				target := dir + "-target"
+				os.Mkdir(target, 0700)
+				return os.Symlink(target, dir)
+			},
+			wantErr: "is not a directory",
+		},
+		{
+			name:    "file",
+			setup:   func(dir string) error { return os.WriteFile(dir, nil, 0600) },
+			wantErr: "is not a directory",
+		},
+		{name: "missing", setup: func(dir string) error { return nil }, wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "warpp")
+			if err := tt.setup(dir); err != nil {
+				t.Fatal(err)
+			}
+			err := checkSocketDir(dir)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	client, _ := startServer(t)
+	if _, err := Listen(client.path); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("second daemon: %v", err)
+	}
+
+	shared := filepath.Join(t.TempDir(), "shared")
+	os.Mkdir(shared, 0777)
+	os.Chmod(shared, 0777)
+	if _, err := Listen(filepath.Join(shared, "daemon.sock")); err == nil {
+		t.Error("listened in a directory others can write to")
+	}
+}
@@ -0,0 +1,303 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"warpp/internal/history"
+	"warpp/internal/rules"
+	"warpp/internal/tmux"
+)
+
+// PollInterval is how often the daemon refreshes sessions and agent states
+const PollInterval = 2 * time.Second
+
+// Server tracks sessions and agent states and serves them on the socket
+type Server struct {
+	backend tmux.Backend
+	engine  *rules.Engine // may be nil
+
+	// refreshMu serializes refreshes from the poll loop and after kill
+	// and launch, so the engine observes polls and subscribers get session
+	// lists in the order they were taken
+	refreshMu sync.Mutex
+
+	mu          sync.Mutex
+	sessions    []tmux.Session
+	subscribers map[chan Event]bool
+	stop        context.CancelFunc
+}
+
+// NewServer returns a server reading tmux through backend and running the
+// rules of engine, which may be nil, on agent state changes
+func NewServer(backend tmux.Backend, engine *rules.Engine) *Server {
+	return &Server{
+		backend:     backend,
+		engine:      engine,
+		subscribers: make(map[chan Event]bool),
+	}
+}
+
+// Listen creates the socket, replacing a stale one, and fails if another
+// daemon is already listening on it or its directory isn't private
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a warpp daemon is already running on %s", path)
+	}
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// Serve polls tmux and answers requests on l until ctx is done or a
+// client asks it to shut down. It closes l.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mu.Lock()
+	s.stop = cancel
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	go s.poll(ctx)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+// poll refreshes the session list every PollInterval, feeding agent states
+// to the rules engine and telling subscribers about changes
+func (s *Server) poll(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		s.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh reloads the session list once
+func (s *Server) refresh(ctx context.Context) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	sessions, err := tmux.GetAllSessions(ctx, s.backend)
+	if err != nil {
+		return
+	}
+	var transitions []rules.Transition
+	if s.engine != nil {
+		transitions = s.engine.Observe(ctx, sessions, time.Now())
+	}
+
+	s.mu.Lock()
+	changed := !reflect.DeepEqual(sessions, s.sessions)
+	s.sessions = sessions
+	s.mu.Unlock()
+
+	for i := range transitions {
+		s.publish(Event{Type: EventTransition, Transition: &transitions[i]})
+	}
+	if changed {
+		s.publish(Event{Type: EventSessions, Sessions: sessions})
+	}
+}
+
+// publish sends an event to every subscriber, dropping it for those that
+// aren't keeping up
+func (s *Server) publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// handle answers the requests of one connection
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(response{JSONRPC: "2.0", Error: &RPCError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == MethodSubscribe {
+			s.subscribe(ctx, conn, encoder)
+			return
+		}
+
+		result, rpcErr := s.dispatch(ctx, req)
+		if req.ID == nil {
+			continue
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				resp.Error = &RPCError{Code: codeServerError, Message: err.Error()}
+			} else {
+				resp.Result = data
+			}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch runs a request and returns its result
+func (s *Server) dispatch(ctx context.Context, req request) (any, *RPCError) {
+	switch req.Method {
+	case MethodList:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.sessions == nil {
+			return []tmux.Session{}, nil
+		}
+		return s.sessions, nil
+	case MethodHistory:
+		h, err := history.Load()
+		if err != nil {
+			return nil, serverError(err)
+		}
+		return h, nil
+	case MethodKill, MethodLaunch:
+		var params NameParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, &RPCError{Code: codeInvalidParams, Message: "params: expected {\"name\": ...}"}
+		}
+		var err error
+		if req.Method == MethodKill {
+			err = s.backend.KillSession(ctx, params.Name)
+		} else {
+			err = s.launch(ctx, params.Name)
+		}
+		if err != nil {
+			return nil, serverError(err)
+		}
+		s.refresh(ctx)
+		return true, nil
+	case MethodShutdown:
+		s.mu.Lock()
+		s.stop()
+		s.mu.Unlock()
+		return true, nil
+	}
+	return nil, &RPCError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// launch starts the layout name unless its session is running, and records
+// the launch
+func (s *Server) launch(ctx context.Context, name string) error {
+	// The list holds a running session before the layout of the same name
+	var session tmux.Session
+	found := false
+	s.mu.Lock()
+	for _, candidate := range s.sessions {
+		if candidate.Name == name && (candidate.IsRunning || candidate.IsLayout) {
+			session, found = candidate, true
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("no session or layout named %s", name)
+	}
+	if !session.IsRunning {
+		if err := tmux.StartLayout(ctx, session, "", ""); err != nil {
+			return err
+		}
+	}
+	// Best-effort, like attaching from the TUI
+	history.Record(name)
+	return nil
+}
+
+// subscribe streams events to conn until it or the daemon goes away
+func (s *Server) subscribe(ctx context.Context, conn net.Conn, encoder *json.Encoder) {
+	events := make(chan Event, 16)
+	s.mu.Lock()
+	s.subscribers[events] = true
+	current := Event{Type: EventSessions, Sessions: s.sessions}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, events)
+		s.mu.Unlock()
+	}()
+
+	// The client sends nothing more; a read returning means it hung up
+	closed := make(chan struct{})
+	go func() {
+		conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+
+	send := func(event Event) error {
+		return encoder.Encode(response{JSONRPC: "2.0", Method: methodEvent, Params: event})
+	}
+	if err := send(current); err != nil {
+		return
+	}
+	for {
+		select {
+		case event := <-events:
+			if err := send(event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// serverError wraps an error for a reply, naming its tmux error kind
+func serverError(err error) *RPCError {
+	rpcErr := &RPCError{Code: codeServerError, Message: err.Error()}
+	for name, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			rpcErr.Data = name
+			break
+		}
+	}
+	return rpcErr
+}
@@ -0,0 +1,161 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxTotalRank bounds the sum of all ranks; past it every rank is aged down
+// so old favourites fade out and the file stays small
+const maxTotalRank = 1000
+
+// Entry is the launch history of one session or layout
+type Entry struct {
+	Rank     float64   `json:"rank"`      // launches, aged down over time
+	LastUsed time.Time `json:"last_used"` // most recent launch or attach
+}
+
+// History records launches and attaches by session name and ranks them by
+// frecency: how often they're used, weighted by how recently
+type History struct {
+	Entries map[string]*Entry `json:"entries"`
+}
+
+// Path returns the location of the history file
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "warpp", "history.json"), nil
+}
+
+// Load reads the history file. A missing file is an empty history.
+func Load() (*History, error) {
+	h := &History{Entries: make(map[string]*Entry)}
+	path, err := Path()
+	if err != nil {
+		return h, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return &History{Entries: make(map[string]*Entry)}, err
+	}
+	if h.Entries == nil {
+		h.Entries = make(map[string]*Entry)
+	}
+	return h, nil
+}
+
+// Save writes the history file, replacing it atomically so concurrent
+// warpp processes never see a partial file
+func (h *History) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "history-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add counts a launch or attach of name at now
+func (h *History) Add(name string, now time.Time) {
+	entry := h.Entries[name]
+	if entry == nil {
+		entry = &Entry{}
+		h.Entries[name] = entry
+	}
+	entry.Rank++
+	entry.LastUsed = now
+
+	total := 0.0
+	for _, e := range h.Entries {
+		total += e.Rank
+	}
+	if total > maxTotalRank {
+		for n, e := range h.Entries {
+			e.Rank *= 0.9
+			if e.Rank < 1 {
+				delete(h.Entries, n)
+			}
+		}
+	}
+}
+
+// Score returns the frecency of name at now; 0 if it was never used. The
+// rank is weighted by the age of the last use.
+func (h *History) Score(name string, now time.Time) float64 {
+	entry := h.Entries[name]
+	if entry == nil {
+		return 0
+	}
+
+	age := now.Sub(entry.LastUsed)
+	switch {
+	case age < time.Hour:
+		return entry.Rank * 4
+	case age < 24*time.Hour:
+		return entry.Rank * 2
+	case age < 7*24*time.Hour:
+		return entry.Rank / 2
+	}
+	return entry.Rank / 4
+}
+
+// Recent returns up to n used names, highest frecency first
+func (h *History) Recent(n int, now time.Time) []string {
+	var names []string
+	for name := range h.Entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := h.Score(names[i], now), h.Score(names[j], now)
+		if si != sj {
+			return si > sj
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// Record loads the history, adds a launch or attach of name and saves it
+func Record(name string) error {
+	h, err := Load()
+	if err != nil {
+		return err
+	}
+	h.Add(name, time.Now())
+	return h.Save()
+}
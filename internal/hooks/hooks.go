@@ -0,0 +1,75 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"warpp/internal/tmux"
+)
+
+// Event is the part of a Claude Code hook payload warpp reads from stdin
+type Event struct {
+	Name             string `json:"hook_event_name"`
+	Message          string `json:"message"`           // Notification text
+	NotificationType string `json:"notification_type"` // e.g. permission_prompt or idle_prompt
+}
+
+// clearState marks events after which the agent is gone
+const clearState = "-"
+
+// State maps a hook event to the agent state it signals: tmux.AgentWaiting,
+// tmux.AgentExecuting or tmux.AgentIdle, clearState when the agent exits,
+// or "" for events that don't change it
+func State(e Event) string {
+	switch e.Name {
+	case "UserPromptSubmit", "PreToolUse", "PostToolUse", "SubagentStop", "PreCompact":
+		return tmux.AgentExecuting
+	case "Stop", "SessionStart":
+		return tmux.AgentIdle
+	case "SessionEnd":
+		return clearState
+	case "Notification":
+		// Claude also notifies when it has sat at its prompt for a while;
+		// everything else asks for permission or an answer
+		if e.NotificationType == "idle_prompt" || strings.Contains(e.Message, "waiting for your input") {
+			return tmux.AgentIdle
+		}
+		return tmux.AgentWaiting
+	}
+	return ""
+}
+
+// Run records the state signalled by the hook payload on stdin in the tmux
+// pane the agent runs in. name overrides the payload's event name when set.
+// Outside tmux there is nothing to record.
+func Run(ctx context.Context, agent, name string, stdin io.Reader) error {
+	var e Event
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+	}
+	if name != "" {
+		e.Name = name
+	}
+
+	paneID := os.Getenv("TMUX_PANE")
+	if paneID == "" {
+		return nil
+	}
+	switch state := State(e); state {
+	case "":
+		return nil
+	case clearState:
+		return tmux.SetPaneAgentState(ctx, paneID, agent, "")
+	default:
+		return tmux.SetPaneAgentState(ctx, paneID, agent, state)
+	}
+}
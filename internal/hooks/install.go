@@ -0,0 +1,204 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Events lists the Claude Code hook events warpp installs itself for
+var Events = []string{
+	"SessionStart",
+	"UserPromptSubmit",
+	"PreToolUse",
+	"PostToolUse",
+	"Notification",
+	"SubagentStop",
+	"PreCompact",
+	"Stop",
+	"SessionEnd",
+}
+
+// toolEvents are the events whose hook entries take a tool matcher
+var toolEvents = map[string]bool{"PreToolUse": true, "PostToolUse": true}
+
+// SettingsPath returns the location of the user's Claude Code settings,
+// honouring CLAUDE_CONFIG_DIR
+func SettingsPath() (string, error) {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "settings.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "settings.json"), nil
+}
+
+// Command returns the hook command to install: this executable followed by
+// the hook subcommand
+func Command() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(exe, " '\"") {
+		exe = "'" + strings.ReplaceAll(exe, "'", `'\''`) + "'"
+	}
+	return exe + " hook", nil
+}
+
+// isWarppCommand reports whether a hook command was installed by warpp
+func isWarppCommand(command string) bool {
+	return strings.HasSuffix(command, " hook") && strings.Contains(command, "warpp")
+}
+
+// Install adds a warpp hook entry for every event in Events to the Claude
+// settings at path, replacing entries from an earlier install. Other
+// settings and hooks are kept.
+func Install(path, command string) error {
+	settings, err := readSettings(path)
+	if err != nil {
+		return err
+	}
+	hooks := objectField(settings, "hooks")
+
+	for _, event := range Events {
+		entries := removeWarppEntries(hooks[event])
+		entry := map[string]any{
+			"hooks": []any{map[string]any{"type": "command", "command": command}},
+		}
+		if toolEvents[event] {
+			entry["matcher"] = "*"
+		}
+		hooks[event] = append(entries, entry)
+	}
+	settings["hooks"] = hooks
+	return writeSettings(path, settings)
+}
+
+// Uninstall removes warpp's hook entries from the Claude settings at path.
+// It returns how many events had one.
+func Uninstall(path string) (int, error) {
+	settings, err := readSettings(path)
+	if err != nil {
+		return 0, err
+	}
+	hooks := objectField(settings, "hooks")
+
+	removed := 0
+	for event, value := range hooks {
+		entries, _ := value.([]any)
+		kept := removeWarppEntries(value)
+		if len(kept) == len(entries) {
+			continue
+		}
+		removed++
+		if len(kept) == 0 {
+			delete(hooks, event)
+		} else {
+			hooks[event] = kept
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if len(hooks) == 0 {
+		delete(settings, "hooks")
+	} else {
+		settings["hooks"] = hooks
+	}
+	return removed, writeSettings(path, settings)
+}
+
+// removeWarppEntries drops warpp's commands from an event's hook entries,
+// and entries left without commands
+func removeWarppEntries(value any) []any {
+	entries, _ := value.([]any)
+	var kept []any
+	for _, e := range entries {
+		entry, ok := e.(map[string]any)
+		if !ok {
+			kept = append(kept, e)
+			continue
+		}
+		commands, _ := entry["hooks"].([]any)
+		var keptCommands []any
+		for _, c := range commands {
+			hook, _ := c.(map[string]any)
+			if command, _ := hook["command"].(string); isWarppCommand(command) {
+				continue
+			}
+			keptCommands = append(keptCommands, c)
+		}
+		if len(keptCommands) == 0 && len(commands) > 0 {
+			continue
+		}
+		if len(keptCommands) != len(commands) {
+			entry["hooks"] = keptCommands
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// objectField returns the JSON object under key, or a new one
+func objectField(settings map[string]any, key string) map[string]any {
+	if object, ok := settings[key].(map[string]any); ok {
+		return object
+	}
+	return make(map[string]any)
+}
+
+// readSettings reads a settings file as a generic JSON object, so fields
+// warpp doesn't know survive a rewrite. A missing file is empty.
+func readSettings(path string) (map[string]any, error) {
+	settings := make(map[string]any)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// writeSettings replaces the settings file atomically, keeping its
+// permissions. A new file is created 0644.
+func writeSettings(path string, settings map[string]any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "settings-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	// CreateTemp makes the file 0600
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testCommand = "/usr/local/bin/warpp hook"
+
+// otherHook is a user's own hook entry, which installs must keep
+var otherHook = map[string]any{
+	"matcher": "Bash",
+	"hooks":   []any{map[string]any{"type": "command", "command": "notify-send done"}},
+}
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string // "" for no file
+		check    func(t *testing.T, settings map[string]any)
+	}{
+		{
+			name: "no settings file",
+			check: func(t *testing.T, settings map[string]any) {
+				hooks := settings["hooks"].(map[string]any)
+				if len(hooks) != len(Events) {
+					t.Errorf("got %d events, want %d", len(hooks), len(Events))
+				}
+				want := []any{map[string]any{
+					"matcher": "*",
+					"hooks":   []any{map[string]any{"type": "command", "command": testCommand}},
+				}}
+				if !reflect.DeepEqual(hooks["PreToolUse"], want) {
+					t.Errorf("PreToolUse = %v", hooks["PreToolUse"])
+				}
+				if _, ok := hooks["Stop"].([]any)[0].(map[string]any)["matcher"]; ok {
+					t.Error("Stop has a matcher")
+				}
+			},
+		},
+		{
+			name:     "other settings and hooks are kept",
+			settings: `{"model": "opus", "hooks": {"PreToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "notify-send done"}]}], "Custom": []}}`,
+			check: func(t *testing.T, settings map[string]any) {
+				if settings["model"] != "opus" {
+					t.Errorf("model = %v", settings["model"])
+				}
+				hooks := settings["hooks"].(map[string]any)
+				if _, ok := hooks["Custom"]; !ok {
+					t.Error("Custom hooks dropped")
+				}
+				entries := hooks["PreToolUse"].([]any)
+				if len(entries) != 2 || !reflect.DeepEqual(entries[0], otherHook) {
+					t.Errorf("PreToolUse = %v", entries)
+				}
+			},
+		},
+		{
+			name:     "an earlier install is replaced",
+			settings: `{"hooks": {"Stop": [{"hooks": [{"type": "command", "command": "/old/path/warpp hook"}, {"type": "command", "command": "say stopped"}]}]}}`,
+			check: func(t *testing.T, settings map[string]any) {
+				entries := settings["hooks"].(map[string]any)["Stop"].([]any)
+				want := []any{
+					map[string]any{"hooks": []any{map[string]any{"type": "command", "command": "say stopped"}}},
+					map[string]any{"hooks": []any{map[string]any{"type": "command", "command": testCommand}}},
+				}
+				if !reflect.DeepEqual(entries, want) {
+					t.Errorf("Stop = %v", entries)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".claude", "settings.json")
+			if tt.settings != "" {
+				writeFile(t, path, tt.settings, 0644)
+			}
+			if err := Install(path, testCommand); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, readJSON(t, path))
+
+			// Installing again changes nothing
+			before, _ := os.ReadFile(path)
+			if err := Install(path, testCommand); err != nil {
+				t.Fatal(err)
+			}
+			if after, _ := os.ReadFile(path); string(after) != string(before) {
+				t.Errorf("second install changed the file:\n%s", after)
+			}
+		})
+	}
+}
+
+func TestInstallInvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	writeFile(t, path, "{not json", 0644)
+	if err := Install(path, testCommand); err == nil {
+		t.Fatal("no error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("settings rewritten: %s", data)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	writeFile(t, path, `{"model": "opus", "hooks": {"PreToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "notify-send done"}]}]}}`, 0644)
+	if err := Install(path, testCommand); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Uninstall(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != len(Events) {
+		t.Errorf("removed %d, want %d", removed, len(Events))
+	}
+	want := map[string]any{
+		"model": "opus",
+		"hooks": map[string]any{"PreToolUse": []any{otherHook}},
+	}
+	if got := readJSON(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+
+	if removed, err := Uninstall(path); err != nil || removed != 0 {
+		t.Errorf("second uninstall: %d, %v", removed, err)
+	}
+}
+
+func TestInstallKeepsMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing os.FileMode // 0 for no file
+		want     os.FileMode
+	}{
+		{name: "new file", want: 0644},
+		{name: "private file", existing: 0600, want: 0600},
+		{name: "shared file", existing: 0664, want: 0664},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.json")
+			if tt.existing != 0 {
+				writeFile(t, path, "{}", tt.existing)
+			}
+			if err := Install(path, testCommand); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("mode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile's mode is subject to the umask
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readJSON(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
@@ -0,0 +1,192 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"warpp/internal/config"
+	"warpp/internal/tmux"
+)
+
+// commandTimeout bounds how long a rule's command may run
+const commandTimeout = time.Minute
+
+// Transition is a change of state of the agent in one pane
+type Transition struct {
+	Session string
+	Agent   string
+	Pane    int    // pane index within its window
+	PaneID  string // tmux pane id, e.g. %3
+	From    string
+	To      string
+	Elapsed time.Duration // time spent in From
+}
+
+// rule is a config.Rule with its templates parsed
+type rule struct {
+	config.Rule
+	command *template.Template
+	message *template.Template
+}
+
+// paneState is the last state seen for an agent's pane
+type paneState struct {
+	agent string
+	state string
+	since time.Time
+}
+
+// Engine watches agent states across polls and runs the configured rules
+// when they change. It's safe for concurrent use; whichever process polls
+// agent states feeds it.
+type Engine struct {
+	rules []rule
+
+	mu     sync.Mutex
+	panes  map[string]paneState // pane id -> last state
+	primed bool                 // a first poll has been seen
+}
+
+// New checks and compiles rules
+func New(rules []config.Rule) (*Engine, error) {
+	e := &Engine{panes: make(map[string]paneState)}
+	for i, r := range rules {
+		if r.To == "" {
+			return nil, fmt.Errorf("rule %d: no target state", i+1)
+		}
+		for _, state := range []string{r.From, r.To} {
+			switch state {
+			case "", tmux.AgentWaiting, tmux.AgentExecuting, tmux.AgentIdle:
+			default:
+				return nil, fmt.Errorf("rule %d: unknown state %q", i+1, state)
+			}
+		}
+		if _, err := filepath.Match(r.Session, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: session: %w", i+1, err)
+		}
+		compiled := rule{Rule: r}
+		var err error
+		if compiled.command, err = template.New("command").Parse(r.Command); err != nil {
+			return nil, fmt.Errorf("rule %d: command: %w", i+1, err)
+		}
+		if compiled.message, err = template.New("message").Parse(r.Message); err != nil {
+			return nil, fmt.Errorf("rule %d: message: %w", i+1, err)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+	return e, nil
+}
+
+// Observe records the agent states of a poll and runs the rules matching
+// each change since the previous one. The first poll only sets the
+// baseline, and agents that just appeared have no previous state. Actions
+// are best-effort and commands run in the background. It returns the
+// transitions seen.
+func (e *Engine) Observe(ctx context.Context, sessions []tmux.Session, now time.Time) []Transition {
+	var transitions []Transition
+	e.mu.Lock()
+	seen := make(map[string]paneState)
+	for _, session := range sessions {
+		for _, agent := range session.Agents {
+			current := paneState{agent: agent.Agent, state: agent.State, since: now}
+			prev, ok := e.panes[agent.PaneID]
+			if ok && prev.agent == agent.Agent && prev.state == agent.State {
+				current.since = prev.since
+			}
+			seen[agent.PaneID] = current
+			if !e.primed || !ok || prev.agent != agent.Agent || prev.state == agent.State {
+				continue
+			}
+			transitions = append(transitions, Transition{
+				Session: session.Name,
+				Agent:   agent.Agent,
+				Pane:    agent.Pane,
+				PaneID:  agent.PaneID,
+				From:    prev.state,
+				To:      agent.State,
+				Elapsed: now.Sub(prev.since),
+			})
+		}
+	}
+	e.panes = seen
+	e.primed = true
+	e.mu.Unlock()
+
+	for _, t := range transitions {
+		for _, r := range e.rules {
+			if r.matches(t) {
+				r.run(ctx, t)
+			}
+		}
+	}
+	return transitions
+}
+
+// matches reports whether the rule applies to a transition
+func (r rule) matches(t Transition) bool {
+	if r.Agent != "" && r.Agent != t.Agent {
+		return false
+	}
+	if r.From != "" && r.From != t.From {
+		return false
+	}
+	if r.Session != "" {
+		if ok, _ := filepath.Match(r.Session, t.Session); !ok {
+			return false
+		}
+	}
+	return r.To == t.To
+}
+
+// templateData is what rule templates see
+type templateData struct {
+	Transition
+	Elapsed string // rounded to the second, e.g. 2m13s
+}
+
+// run performs the rule's actions for a transition
+func (r rule) run(ctx context.Context, t Transition) {
+	data := templateData{Transition: t, Elapsed: t.Elapsed.Round(time.Second).String()}
+	if r.Message != "" || r.Bell {
+		var message strings.Builder
+		r.message.Execute(&message, data)
+		tmux.NotifyClients(ctx, message.String(), r.Bell)
+	}
+	if r.Command == "" {
+		return
+	}
+	var command strings.Builder
+	if err := r.command.Execute(&command, data); err != nil {
+		return
+	}
+
+	// Values are also passed in the environment, which is safer to use in
+	// the shell than templated text
+	cmdCtx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command.String())
+	cmd.Env = append(os.Environ(),
+		"WARPP_SESSION="+t.Session,
+		"WARPP_AGENT="+t.Agent,
+		"WARPP_PANE="+strconv.Itoa(t.Pane),
+		"WARPP_PANE_ID="+t.PaneID,
+		"WARPP_FROM="+t.From,
+		"WARPP_TO="+t.To,
+		"WARPP_ELAPSED="+data.Elapsed,
+	)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return
+	}
+	go func() {
+		cmd.Wait()
+		cancel()
+	}()
+}
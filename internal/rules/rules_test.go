@@ -0,0 +1,197 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"warpp/internal/config"
+	"warpp/internal/tmux"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    config.Rule
+		wantErr string
+	}{
+		{name: "minimal", rule: config.Rule{To: tmux.AgentWaiting}},
+		{name: "full", rule: config.Rule{Agent: "claude", Session: "web-*", From: tmux.AgentExecuting, To: tmux.AgentIdle, Command: "echo {{.Session}}", Message: "{{.Agent}} done"}},
+		{name: "no target state", rule: config.Rule{From: tmux.AgentWaiting}, wantErr: "rule 1: no target state"},
+		{name: "unknown state", rule: config.Rule{From: "sleeping", To: tmux.AgentIdle}, wantErr: `rule 1: unknown state "sleeping"`},
+		{name: "bad glob", rule: config.Rule{Session: "[web", To: tmux.AgentIdle}, wantErr: "rule 1: session:"},
+		{name: "bad command template", rule: config.Rule{To: tmux.AgentIdle, Command: "echo {{.Session"}, wantErr: "rule 1: command:"},
+		{name: "bad message template", rule: config.Rule{To: tmux.AgentIdle, Message: "{{end}}"}, wantErr: "rule 1: message:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.Rule{tt.rule})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// poll builds a session list with one agent per pane id, given as
+// "session/%id=state"
+func poll(agents ...string) []tmux.Session {
+	var sessions []tmux.Session
+	for _, a := range agents {
+		where, state, _ := strings.Cut(a, "=")
+		name, paneID, _ := strings.Cut(where, "/")
+		agent := tmux.AgentState{Agent: "claude", State: state, PaneID: paneID}
+		if len(sessions) > 0 && sessions[len(sessions)-1].Name == name {
+			sessions[len(sessions)-1].Agents = append(sessions[len(sessions)-1].Agents, agent)
+			continue
+		}
+		sessions = append(sessions, tmux.Session{Name: name, Agents: []tmux.AgentState{agent}})
+	}
+	return sessions
+}
+
+func TestObserve(t *testing.T) {
+	type change struct {
+		Session, PaneID, From, To string
+		Elapsed                   time.Duration
+	}
+	tests := []struct {
+		name  string
+		polls [][]tmux.Session
+		want  []change // transitions of the last poll
+	}{
+		{
+			name:  "first poll is the baseline",
+			polls: [][]tmux.Session{poll("web/%1=waiting")},
+		},
+		{
+			name:  "state change",
+			polls: [][]tmux.Session{poll("web/%1=executing"), poll("web/%1=waiting")},
+			want:  []change{{"web", "%1", "executing", "waiting", time.Minute}},
+		},
+		{
+			name:  "unchanged state",
+			polls: [][]tmux.Session{poll("web/%1=executing"), poll("web/%1=executing")},
+		},
+		{
+			name:  "new agent has no previous state",
+			polls: [][]tmux.Session{poll("web/%1=executing"), poll("web/%1=executing", "web/%2=waiting")},
+		},
+		{
+			name: "elapsed counts from the first poll in a state",
+			polls: [][]tmux.Session{
+				poll("api/%3=executing"),
+				poll("api/%3=executing"),
+				poll("api/%3=executing"),
+				poll("api/%3=idle"),
+			},
+			want: []change{{"api", "%3", "executing", "idle", 3 * time.Minute}},
+		},
+		{
+			name: "an agent that went away starts over",
+			polls: [][]tmux.Session{
+				poll("web/%1=executing"),
+				poll(),
+				poll("web/%1=waiting"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			var got []change
+			for i, sessions := range tt.polls {
+				got = nil
+				for _, tr := range e.Observe(context.Background(), sessions, start.Add(time.Duration(i)*time.Minute)) {
+					got = append(got, change{tr.Session, tr.PaneID, tr.From, tr.To, tr.Elapsed})
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tr := Transition{Session: "web-api", Agent: "claude", From: tmux.AgentExecuting, To: tmux.AgentWaiting}
+	tests := []struct {
+		name string
+		rule config.Rule
+		want bool
+	}{
+		{name: "target state", rule: config.Rule{To: tmux.AgentWaiting}, want: true},
+		{name: "other target state", rule: config.Rule{To: tmux.AgentIdle}, want: false},
+		{name: "from", rule: config.Rule{From: tmux.AgentExecuting, To: tmux.AgentWaiting}, want: true},
+		{name: "other from", rule: config.Rule{From: tmux.AgentIdle, To: tmux.AgentWaiting}, want: false},
+		{name: "agent", rule: config.Rule{Agent: "claude", To: tmux.AgentWaiting}, want: true},
+		{name: "other agent", rule: config.Rule{Agent: "codex", To: tmux.AgentWaiting}, want: false},
+		{name: "session glob", rule: config.Rule{Session: "web-*", To: tmux.AgentWaiting}, want: true},
+		{name: "other session", rule: config.Rule{Session: "api", To: tmux.AgentWaiting}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (rule{Rule: tt.rule}).matches(tr); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleCommand(t *testing.T) {
+	// Only agents in sessions matching the glob run the command, which sees
+	// the transition in its template and its environment
+	dir := t.TempDir()
+	e, err := New([]config.Rule{{
+		Session: "web-*",
+		To:      tmux.AgentIdle,
+		Command: `{ echo "{{.Session}} {{.Elapsed}}"; env | grep ^WARPP_ | sort; } > ` + dir + `/$WARPP_SESSION`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	e.Observe(context.Background(), poll("docs/%3=executing", "web-api/%4=executing"), now)
+	e.Observe(context.Background(), poll("docs/%3=idle", "web-api/%4=idle"), now.Add(90*time.Second))
+
+	want := strings.Join([]string{
+		"web-api 1m30s",
+		"WARPP_AGENT=claude",
+		"WARPP_ELAPSED=1m30s",
+		"WARPP_FROM=executing",
+		"WARPP_PANE=0",
+		"WARPP_PANE_ID=%4",
+		"WARPP_SESSION=web-api",
+		"WARPP_TO=idle",
+	}, "\n") + "\n"
+	out := filepath.Join(dir, "web-api")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(out)
+		if err == nil && strings.HasSuffix(string(data), "WARPP_TO=idle\n") {
+			if got := string(data); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("command didn't run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docs")); err == nil {
+		t.Error("command ran for a session outside the glob")
+	}
+}
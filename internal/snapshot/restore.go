@@ -0,0 +1,168 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"warpp/internal/tmux"
+)
+
+// Restore recreates a snapshotted session: its windows with their names
+// and indexes, panes in their directories and layouts, and saved
+// scrollback. command returns what to type into each pane once it exists,
+// "" for nothing. If a step fails the partly built session is killed.
+func Restore(ctx context.Context, s Session, command func(Window, Pane) string) error {
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("session %s has no windows", s.Name)
+	}
+
+	// Start as large as the largest window so every split fits until the
+	// layouts are applied
+	width, height := 0, 0
+	for _, w := range s.Windows {
+		sx, sy := layoutSize(w.Layout)
+		width, height = max(width, sx), max(height, sy)
+	}
+
+	// Panes delete their scrollback file once they've printed it; if the
+	// restore fails, those not handed to a pane yet are deleted here
+	var scrollbackFiles []string
+	restored := false
+	defer func() {
+		if !restored {
+			for _, path := range scrollbackFiles {
+				os.Remove(path)
+			}
+		}
+	}()
+
+	var activeWindow string
+	created := false
+	for _, w := range s.Windows {
+		panes := w.Panes
+		if len(panes) == 0 {
+			panes = []Pane{{Path: s.Path}}
+		}
+
+		// Create the window (or the session, for the first one) with its first pane
+		var args []string
+		if !created {
+			args = []string{"new-session", "-d", "-s", s.Name}
+			if width > 0 && height > 0 {
+				args = append(args, "-x", strconv.Itoa(width), "-y", strconv.Itoa(height))
+			}
+		} else {
+			args = []string{"new-window", "-d", "-t", fmt.Sprintf("=%s:%d", s.Name, w.Index)}
+		}
+		args = append(args, "-P", "-F", "#{window_id} #{pane_id} #{window_index}")
+		if w.Name != "" {
+			args = append(args, "-n", w.Name)
+		}
+		args, scrollbackFiles = paneArgs(args, panes[0], scrollbackFiles)
+
+		output, err := tmux.Run(ctx, "tmux", args...)
+		if err != nil {
+			return abort(s.Name, created, err)
+		}
+		created = true
+		ids := strings.Fields(string(output))
+		if len(ids) < 3 {
+			return abort(s.Name, true, fmt.Errorf("unexpected tmux output: %q", output))
+		}
+		windowID, paneIDs := ids[0], []string{ids[1]}
+		if ids[2] != strconv.Itoa(w.Index) {
+			// The session's first window starts at base-index
+			if _, err := tmux.Run(ctx, "tmux", "move-window", "-s", windowID, "-t", fmt.Sprintf("=%s:%d", s.Name, w.Index)); err != nil {
+				return abort(s.Name, true, err)
+			}
+		}
+
+		// Split each pane off the previous one so they keep their order,
+		// then lay them out as they were
+		for _, pane := range panes[1:] {
+			var args []string
+			args, scrollbackFiles = paneArgs([]string{"split-window", "-t", paneIDs[len(paneIDs)-1], "-P", "-F", "#{pane_id}"}, pane, scrollbackFiles)
+			output, err := tmux.Run(ctx, "tmux", args...)
+			if err != nil {
+				return abort(s.Name, true, err)
+			}
+			paneIDs = append(paneIDs, strings.TrimSpace(string(output)))
+		}
+		if w.Layout != "" {
+			if _, err := tmux.Run(ctx, "tmux", "select-layout", "-t", windowID, w.Layout); err != nil {
+				return abort(s.Name, true, err)
+			}
+		}
+
+		for i, pane := range panes {
+			if pane.Active {
+				tmux.Run(ctx, "tmux", "select-pane", "-t", paneIDs[i])
+			}
+			if cmd := command(w, pane); cmd != "" {
+				if _, err := tmux.Run(ctx, "tmux", "send-keys", "-t", paneIDs[i], cmd, "Enter"); err != nil {
+					return abort(s.Name, true, err)
+				}
+			}
+		}
+		if w.Active || activeWindow == "" {
+			activeWindow = windowID
+		}
+	}
+
+	restored = true
+	_, err := tmux.Run(ctx, "tmux", "select-window", "-t", activeWindow)
+	return err
+}
+
+// paneArgs adds the pane's start directory to a new-session, new-window or
+// split-window command, and a command printing its saved scrollback before
+// starting the user's shell. The scrollback goes to a temporary file, added
+// to files.
+func paneArgs(args []string, pane Pane, files []string) ([]string, []string) {
+	if pane.Path != "" {
+		args = append(args, "-c", pane.Path)
+	}
+	if pane.Scrollback == "" {
+		return args, files
+	}
+	f, err := os.CreateTemp("", "warpp-scrollback-*")
+	if err != nil {
+		return args, files
+	}
+	_, err = f.WriteString(pane.Scrollback + "\n")
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return args, files
+	}
+	// Several arguments make tmux exec them directly rather than through
+	// default-shell, whatever its syntax
+	return append(args, "sh", "-c", `cat "$1"; rm -f "$1"; exec "${SHELL:-/bin/sh}" -l`, "sh", f.Name()), append(files, f.Name())
+}
+
+// layoutSize returns the window size a layout string was taken at, e.g.
+// 200x50 for "b25d,200x50,0,0{...}"
+func layoutSize(layout string) (int, int) {
+	parts := strings.SplitN(layout, ",", 3)
+	if len(parts) < 2 {
+		return 0, 0
+	}
+	sx, sy, ok := strings.Cut(parts[1], "x")
+	if !ok {
+		return 0, 0
+	}
+	width, _ := strconv.Atoi(sx)
+	height, _ := strconv.Atoi(sy)
+	return width, height
+}
+
+// abort kills a partly restored session and returns err
+func abort(sessionName string, created bool, err error) error {
+	if created {
+		tmux.Run(context.Background(), "tmux", "kill-session", "-t", "="+sessionName)
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"warpp/internal/tmux"
+)
+
+// timeFormat names snapshot files so they sort by age
+const timeFormat = "2006-01-02T15-04-05"
+
+// Snapshot is the state of every running tmux session at one point in time
+type Snapshot struct {
+	Created  time.Time `json:"created"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Session is a snapshotted session and its windows in index order
+type Session struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"` // session_path, where new windows start
+	Windows []Window `json:"windows"`
+}
+
+// Window is a snapshotted window and its panes in index order
+type Window struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Layout string `json:"layout"` // window_layout, as select-layout takes it
+	Active bool   `json:"active"`
+	Panes  []Pane `json:"panes"`
+}
+
+// Pane is a snapshotted pane
+type Pane struct {
+	Index      int    `json:"index"`
+	Path       string `json:"path"`
+	Command    string `json:"command,omitempty"` // command line of the job running in the pane's shell
+	Agent      string `json:"agent,omitempty"`   // coding agent detected in the pane
+	Active     bool   `json:"active"`
+	Scrollback string `json:"scrollback,omitempty"`
+}
+
+// PaneCount returns the number of panes across all windows
+func (s Session) PaneCount() int {
+	n := 0
+	for _, w := range s.Windows {
+		n += len(w.Panes)
+	}
+	return n
+}
+
+// Dir returns the directory snapshots are saved in
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "warpp", "snapshots"), nil
+}
+
+// Take snapshots every running session. Commands come from the processes
+// the backend lists, agents from the registered detectors, and scrollback
+// lines of history are saved per pane.
+func Take(ctx context.Context, b tmux.Backend, scrollback int) (*Snapshot, error) {
+	return take(ctx, b, scrollback, "")
+}
+
+// TakeSession snapshots one running session, like Take
+func TakeSession(ctx context.Context, b tmux.Backend, name string, scrollback int) (*Session, error) {
+	snap, err := take(ctx, b, scrollback, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(snap.Sessions) == 0 {
+		return nil, fmt.Errorf("session %s: %w", name, tmux.ErrSessionNotFound)
+	}
+	return &snap.Sessions[0], nil
+}
+
+// take snapshots the running sessions through the backend, or only the one
+// named only when it isn't empty
+func take(ctx context.Context, b tmux.Backend, scrollback int, only string) (*Snapshot, error) {
+	sessions, err := b.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Agents by pane id, and the processes to find each pane's job
+	agents := make(map[string]string)
+	for _, states := range tmux.GetAgentStates(ctx, b) {
+		for _, state := range states {
+			agents[state.PaneID] = state.Agent
+		}
+	}
+	procs, _ := b.ListProcesses(ctx)
+
+	snap := &Snapshot{Created: time.Now()}
+	for _, info := range sessions {
+		if only != "" && info.Name != only {
+			continue
+		}
+		windows, err := b.ListWindows(ctx, info.Name)
+		if errors.Is(err, tmux.ErrSessionNotFound) {
+			// Killed since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		session := Session{Name: info.Name, Path: info.Path}
+		for _, w := range windows {
+			window := Window{Index: w.Index, Name: w.Name, Layout: w.Layout, Active: w.Active}
+			for _, p := range w.Panes {
+				pane := Pane{
+					Index:   p.Index,
+					Path:    p.Path,
+					Command: paneCommand(procs, p.PID),
+					Agent:   agents[p.ID],
+					Active:  p.Active,
+				}
+				if scrollback > 0 {
+					// Best-effort: a pane that can't be captured is restored empty
+					content, _ := b.CapturePaneByID(ctx, p.ID, scrollback, true)
+					pane.Scrollback = strings.TrimRight(content, "\n ")
+				}
+				window.Panes = append(window.Panes, pane)
+			}
+			session.Windows = append(session.Windows, window)
+		}
+		snap.Sessions = append(snap.Sessions, session)
+	}
+	return snap, nil
+}
+
+// paneCommand returns the command line of the job a pane's shell runs, or
+// "" when the shell is idle
+func paneCommand(procs []tmux.Process, shellPID int) string {
+	for _, proc := range procs {
+		if proc.PPID == shellPID && shellPID > 0 {
+			return proc.Command
+		}
+	}
+	return ""
+}
+
+// Save writes the snapshot to a new file in Dir, named after its creation
+// time, and returns the path
+func Save(s *Snapshot) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return writeJSON(dir, s.Created.Format(timeFormat)+".json", s)
+}
+
+// writeJSON writes v to a file in dir, replacing it atomically so a crash
+// never leaves a partial file, and returns its path
+func writeJSON(dir, name string, v any) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	tmp, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// List returns the saved snapshot files, newest first
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return newestFirst(dir)
+}
+
+// newestFirst returns the JSON files in dir, whose names start with their
+// time, newest first
+func newestFirst(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// Prune deletes all but the newest keep snapshots
+func Prune(keep int) error {
+	paths, err := List()
+	if err != nil {
+		return err
+	}
+	return removeAfter(paths, keep)
+}
+
+// removeAfter deletes the files after the first keep
+func removeAfter(paths []string, keep int) error {
+	for i := keep; i < len(paths); i++ {
+		if err := os.Remove(paths[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads a snapshot file
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Latest returns the newest saved snapshot and its path, or os.ErrNotExist
+// when there is none
+func Latest() (*Snapshot, string, error) {
+	paths, err := List()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(paths) == 0 {
+		return nil, "", os.ErrNotExist
+	}
+	s, err := Load(paths[0])
+	return s, paths[0], err
+}
@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Trashed is a killed session, kept so the kill can be undone
+type Trashed struct {
+	Killed  time.Time `json:"killed"`
+	Session Session   `json:"session"`
+	Path    string    `json:"-"` // the file it's kept in
+}
+
+// TrashDir returns the directory killed sessions are kept in
+func TrashDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "warpp", "trash"), nil
+}
+
+// AddToTrash keeps a session about to be killed, and deletes all but the
+// newest keep sessions in the trash
+func AddToTrash(s Session, keep int) error {
+	dir, err := TrashDir()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// Nanoseconds keep two kills within a second apart
+	name := now.Format(timeFormat+".000000000") + ".json"
+	if _, err := writeJSON(dir, name, Trashed{Killed: now, Session: s}); err != nil {
+		return err
+	}
+	paths, err := newestFirst(dir)
+	if err != nil {
+		return err
+	}
+	return removeAfter(paths, keep)
+}
+
+// ListTrash returns the killed sessions in the trash, newest first
+func ListTrash() ([]Trashed, error) {
+	dir, err := TrashDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := newestFirst(dir)
+	if err != nil {
+		return nil, err
+	}
+	var trash []Trashed
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t := Trashed{Path: path}
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		trash = append(trash, t)
+	}
+	return trash, nil
+}
+
+// Remove takes a session out of the trash once it's restored
+func (t Trashed) Remove() error {
+	return os.Remove(t.Path)
+}
@@ -0,0 +1,237 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Agent states, from most to least in need of attention
+const (
+	AgentWaiting   = "waiting"   // asking for input or approval
+	AgentExecuting = "executing" // working on a request
+	AgentIdle      = "idle"      // running, at its prompt
+)
+
+// AgentStateOption is the pane user option where warpp hook records the
+// state an agent reports about itself, as agent:state
+const AgentStateOption = "@warpp_agent_state"
+
+// agentStateLines is how many lines at the bottom of a pane are checked for
+// state markers, so old output higher up doesn't count
+const agentStateLines = 15
+
+// AgentDetector recognises a coding agent by the command line of a process
+// in a pane, and its state by the pane's visible text
+type AgentDetector struct {
+	Name      string
+	Process   *regexp.Regexp
+	Executing []*regexp.Regexp
+	Idle      []*regexp.Regexp
+	Waiting   []*regexp.Regexp
+}
+
+// NewAgentDetector compiles an agent's process and state patterns
+func NewAgentDetector(name, process string, executing, idle, waiting []string) (AgentDetector, error) {
+	d := AgentDetector{Name: name}
+	if process == "" {
+		return d, fmt.Errorf("agent %s: no process pattern", name)
+	}
+	var err error
+	if d.Process, err = regexp.Compile(process); err != nil {
+		return d, fmt.Errorf("agent %s: process: %w", name, err)
+	}
+	for _, set := range []struct {
+		patterns []string
+		out      *[]*regexp.Regexp
+		field    string
+	}{
+		{executing, &d.Executing, "executing"},
+		{idle, &d.Idle, "idle"},
+		{waiting, &d.Waiting, "waiting"},
+	} {
+		for _, pattern := range set.patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return d, fmt.Errorf("agent %s: %s: %w", name, set.field, err)
+			}
+			*set.out = append(*set.out, re)
+		}
+	}
+	return d, nil
+}
+
+// State classifies the agent from the bottom of its pane. Waiting markers
+// win over executing ones; with no marker the agent is idle.
+func (d AgentDetector) State(content string) string {
+	switch {
+	case matchAny(d.Waiting, content):
+		return AgentWaiting
+	case matchAny(d.Executing, content):
+		return AgentExecuting
+	}
+	return AgentIdle
+}
+
+// bottomLines returns the last n lines of content, ignoring blank lines
+// below the last output
+func bottomLines(content string, n int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n "), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AgentState is a coding agent found running in a pane
+type AgentState struct {
+	Agent  string // detector name, e.g. claude
+	State  string // AgentWaiting, AgentExecuting or AgentIdle
+	Pane   int    // pane index within its window
+	PaneID string // tmux pane id, e.g. %3
+}
+
+// agentPriority orders states by how much they need attention
+var agentPriority = map[string]int{AgentWaiting: 3, AgentExecuting: 2, AgentIdle: 1}
+
+// TopAgent returns the session's agent that most needs attention: waiting
+// before executing before idle, then the first in pane order
+func (s Session) TopAgent() (AgentState, bool) {
+	var top AgentState
+	for _, agent := range s.Agents {
+		if agentPriority[agent.State] > agentPriority[top.State] {
+			top = agent
+		}
+	}
+	return top, top.Agent != ""
+}
+
+var (
+	agentMu        sync.Mutex
+	agentDetectors []AgentDetector
+)
+
+// SetAgentDetectors replaces the detectors GetAllSessions uses. The first
+// detector matching a process wins. With none set, no agents are detected.
+func SetAgentDetectors(detectors []AgentDetector) {
+	agentMu.Lock()
+	defer agentMu.Unlock()
+	agentDetectors = append([]AgentDetector(nil), detectors...)
+}
+
+// AgentDetectors returns the registered detectors
+func AgentDetectors() []AgentDetector {
+	agentMu.Lock()
+	defer agentMu.Unlock()
+	return append([]AgentDetector(nil), agentDetectors...)
+}
+
+// SetPaneAgentState records the state an agent reports in its pane. An
+// empty state clears it.
+func SetPaneAgentState(ctx context.Context, paneID, agent, state string) error {
+	args := []string{"set-option", "-p", "-t", paneID}
+	if state == "" {
+		args = append(args, "-u", AgentStateOption)
+	} else {
+		args = append(args, AgentStateOption, agent+":"+state)
+	}
+	_, err := Run(ctx, "tmux", args...)
+	return err
+}
+
+// hookState returns the state a pane's agent reported through warpp hook,
+// if it's the agent found running there
+func hookState(pane PaneInfo, agent string) (string, bool) {
+	name, state, ok := strings.Cut(pane.HookState, ":")
+	if !ok || name != agent || agentPriority[state] == 0 {
+		return "", false
+	}
+	return state, true
+}
+
+// GetAgentStates returns the agents running in each session's panes, keyed
+// by session name, in pane order. Processes are matched to panes by
+// ancestry and TTY (see processPanes). An agent's state comes from its
+// hooks when it reports one, and otherwise from the bottom of its pane.
+// Detection is best-effort: sessions whose panes or processes can't be read
+// are left out.
+func GetAgentStates(ctx context.Context, b Backend) map[string][]AgentState {
+	result := make(map[string][]AgentState)
+	detectors := AgentDetectors()
+	if len(detectors) == 0 {
+		return result
+	}
+
+	// Step 1: Find agent processes
+	procs, err := b.ListProcesses(ctx)
+	if err != nil {
+		return result
+	}
+	agentProcs := make(map[int]int) // pid -> detector index
+	for _, proc := range procs {
+		for i, d := range detectors {
+			if d.Process.MatchString(proc.Command) {
+				agentProcs[proc.PID] = i
+				break
+			}
+		}
+	}
+	if len(agentProcs) == 0 {
+		return result
+	}
+
+	// Step 2: Map them to panes, one agent per pane
+	allPanes, err := b.ListAllPanes(ctx)
+	if err != nil {
+		return result
+	}
+	panes := processPanes(procs, allPanes)
+	paneAgents := make(map[string]int) // pane id -> detector index
+	for _, pane := range allPanes {
+		paneAgents[pane.ID] = -1
+	}
+	for pid, detector := range agentProcs {
+		pane, ok := panes[pid]
+		if !ok {
+			continue
+		}
+		// Prefer the earlier detector when several agents share a pane
+		if current := paneAgents[pane.ID]; current == -1 || detector < current {
+			paneAgents[pane.ID] = detector
+		}
+	}
+
+	// Step 3: Classify each agent by its hooks or the bottom of its pane
+	for _, pane := range allPanes {
+		detector := paneAgents[pane.ID]
+		if detector < 0 {
+			continue
+		}
+		d := detectors[detector]
+		state, ok := hookState(pane, d.Name)
+		if !ok {
+			state = AgentIdle
+			if content, err := b.CapturePaneByID(ctx, pane.ID, 0, false); err == nil {
+				state = d.State(bottomLines(content, agentStateLines))
+			}
+		}
+		result[pane.Session] = append(result[pane.Session], AgentState{
+			Agent:  d.Name,
+			State:  state,
+			Pane:   pane.Index,
+			PaneID: pane.ID,
+		})
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package tmux
+
+import (
+	"context"
+	"time"
+)
+
+// Backend abstracts the tmux server and process table that warpp reads from,
+// so the TUI can run against a live tmux or a scripted in-memory fake.
+// Errors are *CommandError values classified by the Err* kinds.
+type Backend interface {
+	// ListSessions returns running tmux sessions with their metadata
+	ListSessions(ctx context.Context) ([]SessionInfo, error)
+	// ListPanes returns the panes in the active window of a session
+	ListPanes(ctx context.Context, sessionName string) ([]PaneInfo, error)
+	// ListWindows returns every window of a session with its panes, including
+	// each pane's current command and path
+	ListWindows(ctx context.Context, sessionName string) ([]WindowInfo, error)
+	// ListAllPanes returns every pane of every session, with Session and TTY set
+	ListAllPanes(ctx context.Context) ([]PaneInfo, error)
+	// CapturePane returns the last height lines of a pane (ActivePane for the
+	// session's active pane), keeping ANSI escape sequences when ansi is true
+	CapturePane(ctx context.Context, sessionName string, paneIndex int, height int, ansi bool) (string, error)
+	// CapturePaneByID is CapturePane for a pane in any window, by tmux pane id
+	CapturePaneByID(ctx context.Context, paneID string, height int, ansi bool) (string, error)
+	// KillSession kills a running tmux session
+	KillSession(ctx context.Context, name string) error
+	// ListProcesses returns running processes and their controlling TTYs
+	ListProcesses(ctx context.Context) ([]Process, error)
+}
+
+// SessionInfo is a running session as listed by tmux
+type SessionInfo struct {
+	Name     string
+	Created  time.Time
+	Activity time.Time // last activity in any of its panes
+	Attached int       // number of attached clients
+	Windows  int
+	Alerts   string // session_alerts: windows with bell (!), activity (#) or silence (~) flags, e.g. "1!,3#"
+	Path     string // session_path, where new windows start
+}
+
+// ActivePane targets the active pane of a session in CapturePane
+const ActivePane = -1
+
+// Process is a running process as seen by the backend
+type Process struct {
+	PID     int
+	PPID    int
+	TTY     string // controlling terminal device, e.g. /dev/pts/3 or /dev/ttys044; "" if none
+	Command string // full command line
+}
@@ -0,0 +1,90 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Binding describes the key that opens warpp in a tmux popup
+type Binding struct {
+	Key    string // pressed after the prefix
+	Width  string // popup width, e.g. 80%
+	Height string // popup height, e.g. 75%
+}
+
+// BindingSnippet returns tmux configuration binding prefix+Key to run
+// command in a popup that closes when it exits. The popup starts in the
+// current pane's directory so new sessions open where the user is.
+func BindingSnippet(b Binding, command string) string {
+	// Single quotes keep tmux from expanding anything in the command
+	command = "'" + strings.ReplaceAll(command, "'", `'\''`) + "'"
+	return fmt.Sprintf("# Generated by warpp install-tmux-bindings\n"+
+		"bind-key %s display-popup -E -w %s -h %s -d '#{pane_current_path}' %s\n",
+		b.Key, b.Width, b.Height, command)
+}
+
+// ConfPath returns the user's tmux.conf: ~/.tmux.conf, or the XDG location
+// when only that one exists
+func ConfPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	classic := filepath.Join(home, ".tmux.conf")
+	if _, err := os.Stat(classic); err == nil {
+		return classic, nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	xdg := filepath.Join(configHome, "tmux", "tmux.conf")
+	if _, err := os.Stat(xdg); err == nil {
+		return xdg, nil
+	}
+	return classic, nil
+}
+
+// InstallSnippet writes snippet to snippetPath and makes confPath source it,
+// appending a source-file line unless one is already there. It reports
+// whether confPath was changed.
+func InstallSnippet(confPath, snippetPath, snippet string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(snippetPath), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(snippetPath, []byte(snippet), 0644); err != nil {
+		return false, err
+	}
+
+	conf, err := os.ReadFile(confPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if strings.Contains(string(conf), snippetPath) {
+		return false, nil
+	}
+
+	var line strings.Builder
+	if len(conf) > 0 {
+		if !strings.HasSuffix(string(conf), "\n") {
+			line.WriteString("\n")
+		}
+		line.WriteString("\n")
+	}
+	fmt.Fprintf(&line, "# warpp popup binding\nsource-file -q '%s'\n", snippetPath)
+
+	if err := os.MkdirAll(filepath.Dir(confPath), 0755); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(confPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.WriteString(line.String()); err != nil {
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
@@ -0,0 +1,737 @@
+package tmux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	controlRequestTimeout = 2 * time.Second
+	controlRedialInterval = 5 * time.Second
+	// User sessions don't send layout notifications to a client attached
+	// elsewhere, so cached panes expire after controlStructureTTL. Contents
+	// of the watched window are kept until its %output, others expire after
+	// controlContentTTL.
+	controlStructureTTL = 2 * time.Second
+	controlContentTTL   = time.Second
+)
+
+// ControlSession is the session warpp's control-mode client attaches to.
+// Attaching a client to a session marks it as just used, so the client never
+// attaches to the user's sessions; this one is left out of every listing and
+// goes away when its last client detaches. The ~ sorts it after any session
+// name a user is likely to pick in tmux ls.
+const ControlSession = "~warpp"
+
+// ControlBackend keeps a single tmux control-mode client (tmux -C) open and
+// serves sessions, panes and pane contents from an in-process model. The model
+// is invalidated by control-mode notifications and expires, and cache misses
+// are answered over the same connection, so reads never fork tmux. When no
+// connection can be made it falls back to ExecBackend.
+//
+// The window of the pane last previewed through CapturePane is linked into
+// ControlSession, so tmux sends %output for its panes. warpp has no terminal
+// emulator to apply that output to, so it marks the pane's cached content
+// stale and the next read re-captures it; without output the content is
+// served from the cache however old it is.
+type ControlBackend struct {
+	fallback *ExecBackend
+
+	dialMu   sync.Mutex
+	conn     *controlConn
+	lastDial time.Time
+
+	mu            sync.Mutex // guards the model below
+	sessions      []SessionInfo
+	sessionsAt    time.Time
+	sessionsValid bool
+	panes         map[string]cachedPanes    // session -> panes of its active window
+	windows       map[string]cachedWindows  // session -> all of its windows
+	allPanes      cachedPanes               // panes of every session
+	captures      map[string]*cachedCapture // pane id + mode -> captured content
+	watched       string                    // window linked into ControlSession
+	watchedPanes  map[string]bool           // panes of the watched window
+	outputSeq     map[string]int            // pane id -> %output lines seen
+
+	watchMu sync.Mutex // one change to the watched window at a time
+}
+
+type cachedPanes struct {
+	panes []PaneInfo
+	at    time.Time
+	valid bool
+}
+
+type cachedWindows struct {
+	windows []WindowInfo
+	at      time.Time
+}
+
+type cachedCapture struct {
+	height int
+	text   string
+	at     time.Time
+	live   bool // kept until the pane's next %output instead of expiring
+}
+
+// NewControlBackend returns a Backend that reads through a persistent tmux
+// control-mode client. The connection is made lazily on first use.
+func NewControlBackend() *ControlBackend {
+	return &ControlBackend{
+		fallback:  NewExecBackend(),
+		panes:     make(map[string]cachedPanes),
+		windows:   make(map[string]cachedWindows),
+		captures:  make(map[string]*cachedCapture),
+		outputSeq: make(map[string]int),
+	}
+}
+
+// Close detaches the control-mode client
+func (b *ControlBackend) Close() {
+	b.dialMu.Lock()
+	defer b.dialMu.Unlock()
+
+	if b.conn != nil {
+		b.conn.close()
+		b.conn = nil
+	}
+}
+
+// ListSessions returns running sessions, refreshed on %sessions-changed.
+// Activity and attached clients change without a notification, so the
+// cache also expires.
+func (b *ControlBackend) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	b.mu.Lock()
+	if b.sessionsValid && time.Since(b.sessionsAt) < controlStructureTTL {
+		sessions := append([]SessionInfo{}, b.sessions...)
+		b.mu.Unlock()
+		return sessions, nil
+	}
+	b.mu.Unlock()
+
+	c := b.connection()
+	if c == nil {
+		return b.fallback.ListSessions(ctx)
+	}
+	lines, err := c.request(ctx, "list-sessions -F '"+sessionFormat+"'")
+	if err != nil {
+		return nil, err
+	}
+	sessions := parseSessionLines(lines)
+	if clients, err := c.request(ctx, "list-clients -F '"+clientFormat+"'"); err == nil {
+		discountControlClients(sessions, clients)
+	}
+
+	b.mu.Lock()
+	b.sessions = sessions
+	b.sessionsAt = time.Now()
+	b.sessionsValid = true
+	b.mu.Unlock()
+	return append([]SessionInfo{}, sessions...), nil
+}
+
+// ListPanes returns the panes in the active window of a session
+func (b *ControlBackend) ListPanes(ctx context.Context, sessionName string) ([]PaneInfo, error) {
+	b.mu.Lock()
+	cached := b.panes[sessionName]
+	if cached.valid && time.Since(cached.at) < controlStructureTTL {
+		b.mu.Unlock()
+		return append([]PaneInfo(nil), cached.panes...), nil
+	}
+	b.mu.Unlock()
+
+	c := b.connection()
+	if c == nil {
+		return b.fallback.ListPanes(ctx, sessionName)
+	}
+
+	lines, err := c.request(ctx, "list-panes -t "+exactTarget(sessionName)+" -F '"+paneFormat+"'")
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []PaneInfo
+	for _, line := range lines {
+		if pane, ok := parsePaneLine(sessionName, line); ok {
+			panes = append(panes, pane)
+		}
+	}
+
+	b.mu.Lock()
+	b.panes[sessionName] = cachedPanes{panes: panes, at: time.Now(), valid: true}
+	b.mu.Unlock()
+	return append([]PaneInfo(nil), panes...), nil
+}
+
+// ListWindows returns every window of a session with its panes. Pane commands
+// and paths change without a notification, so the cache always expires.
+func (b *ControlBackend) ListWindows(ctx context.Context, sessionName string) ([]WindowInfo, error) {
+	b.mu.Lock()
+	if cached, ok := b.windows[sessionName]; ok && time.Since(cached.at) < controlStructureTTL {
+		b.mu.Unlock()
+		return cloneWindows(cached.windows), nil
+	}
+	b.mu.Unlock()
+
+	c := b.connection()
+	if c == nil {
+		return b.fallback.ListWindows(ctx, sessionName)
+	}
+	lines, err := c.request(ctx, "list-panes -s -t "+exactTarget(sessionName)+" -F '"+windowFormat+"'")
+	if err != nil {
+		return nil, err
+	}
+	windows := parseWindowLines(sessionName, lines)
+
+	b.mu.Lock()
+	b.windows[sessionName] = cachedWindows{windows: windows, at: time.Now()}
+	b.mu.Unlock()
+	return cloneWindows(windows), nil
+}
+
+// cloneWindows copies windows and their pane slices
+func cloneWindows(windows []WindowInfo) []WindowInfo {
+	out := make([]WindowInfo, len(windows))
+	for i, w := range windows {
+		w.Panes = append([]PaneInfo(nil), w.Panes...)
+		out[i] = w
+	}
+	return out
+}
+
+// ListAllPanes returns every pane on the server with its session and TTY
+func (b *ControlBackend) ListAllPanes(ctx context.Context) ([]PaneInfo, error) {
+	b.mu.Lock()
+	if b.allPanes.valid && time.Since(b.allPanes.at) < controlStructureTTL {
+		panes := append([]PaneInfo(nil), b.allPanes.panes...)
+		b.mu.Unlock()
+		return panes, nil
+	}
+	b.mu.Unlock()
+
+	c := b.connection()
+	if c == nil {
+		return b.fallback.ListAllPanes(ctx)
+	}
+	lines, err := c.request(ctx, "list-panes -a -F '"+allPaneFormat+"'")
+	if err != nil {
+		return nil, err
+	}
+	panes := parseAllPaneLines(lines)
+
+	b.mu.Lock()
+	b.allPanes = cachedPanes{panes: panes, at: time.Now(), valid: true}
+	b.mu.Unlock()
+	return append([]PaneInfo(nil), panes...), nil
+}
+
+// CapturePane returns pane content from the cache, re-capturing over the
+// control connection once the pane had output or the entry has expired. The
+// pane's window is watched from then on.
+func (b *ControlBackend) CapturePane(ctx context.Context, sessionName string, paneIndex int, height int, ansi bool) (string, error) {
+	c := b.connection()
+	if c == nil {
+		return b.fallback.CapturePane(ctx, sessionName, paneIndex, height, ansi)
+	}
+
+	panes, err := b.ListPanes(ctx, sessionName)
+	if err != nil {
+		return "", err
+	}
+	target := ""
+	for _, pane := range panes {
+		if pane.Index == paneIndex || (paneIndex == ActivePane && pane.Active) {
+			target = pane.ID
+			break
+		}
+	}
+	if target == "" {
+		return "", newCommandError("tmux", []string{"capture-pane"},
+			fmt.Sprintf("can't find pane: %s:.%d", sessionName, paneIndex), nil)
+	}
+
+	b.watch(ctx, c, target)
+	return b.capture(ctx, c, target, height, ansi)
+}
+
+// CapturePaneByID returns a pane's content by pane id, from the cache while
+// it's fresh
+func (b *ControlBackend) CapturePaneByID(ctx context.Context, paneID string, height int, ansi bool) (string, error) {
+	c := b.connection()
+	if c == nil {
+		return b.fallback.CapturePaneByID(ctx, paneID, height, ansi)
+	}
+	return b.capture(ctx, c, paneID, height, ansi)
+}
+
+// capture runs capture-pane for a pane id through the cache
+func (b *ControlBackend) capture(ctx context.Context, c *controlConn, target string, height int, ansi bool) (string, error) {
+	key := captureKey(target, ansi)
+	b.mu.Lock()
+	cached := b.captures[key]
+	if cached != nil && cached.height == height && (cached.live || time.Since(cached.at) < controlContentTTL) {
+		text := cached.text
+		b.mu.Unlock()
+		return text, nil
+	}
+	// Output notified before the reply is already counted when it arrives
+	seq := b.outputSeq[target]
+	b.mu.Unlock()
+
+	cmd := "capture-pane -p -t " + target
+	if ansi {
+		cmd += " -e"
+	}
+	cmd += fmt.Sprintf(" -S -%d", height)
+	lines, err := c.request(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	text := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		text += "\n"
+	}
+
+	b.mu.Lock()
+	live := b.watchedPanes[target] && b.outputSeq[target] == seq
+	b.captures[key] = &cachedCapture{height: height, text: text, at: time.Now(), live: live}
+	b.mu.Unlock()
+	return text, nil
+}
+
+// captureKey is the captures key of a pane id in a capture mode
+func captureKey(paneID string, ansi bool) string {
+	return fmt.Sprintf("%s|%t", paneID, ansi)
+}
+
+// watch links the window of paneID into ControlSession in place of the
+// window watched before, so that tmux sends %output for its panes. Errors
+// are ignored: an unwatched pane's content just expires.
+func (b *ControlBackend) watch(ctx context.Context, c *controlConn, paneID string) {
+	b.watchMu.Lock()
+	defer b.watchMu.Unlock()
+
+	b.mu.Lock()
+	watching, old := b.watchedPanes[paneID], b.watched
+	b.mu.Unlock()
+	if watching {
+		return
+	}
+
+	lines, err := c.request(ctx, "list-panes -t "+paneID+" -F '#{window_id} #{pane_id}'")
+	if err != nil || len(lines) == 0 {
+		return
+	}
+	window := ""
+	panes := make(map[string]bool)
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			window = fields[0]
+			panes[fields[1]] = true
+		}
+	}
+	if window == "" {
+		return
+	}
+	if window != old {
+		// -d leaves the window ControlSession's client sees alone
+		if _, err := c.request(ctx, "link-window -d -s "+window+" -t "+quote("="+ControlSession+":")); err != nil {
+			return
+		}
+	}
+
+	b.mu.Lock()
+	if window != old {
+		b.dropWatchLocked()
+	}
+	b.watched = window
+	b.watchedPanes = panes
+	b.mu.Unlock()
+
+	if old != "" && window != old {
+		b.unwatch(ctx, c, old)
+	}
+}
+
+// unwatch removes ControlSession's link to window, killing the window when
+// that was its last link, as when its session was killed while watched
+func (b *ControlBackend) unwatch(ctx context.Context, c *controlConn, window string) {
+	if _, err := c.request(ctx, "unlink-window -t "+quote("="+ControlSession+":"+window)); err == nil {
+		return
+	}
+	lines, err := c.request(ctx, "display-message -p -t "+window+" '#{window_linked_sessions}'")
+	if err == nil && len(lines) == 1 && lines[0] == "1" {
+		c.request(ctx, "kill-window -t "+window)
+	}
+}
+
+// reconcileWatch checks the watched window after one of its links went away.
+// It's forgotten once ControlSession doesn't have it anymore, and killed
+// when ControlSession holds its last link, so that it doesn't outlive the
+// session it was watched in.
+func (b *ControlBackend) reconcileWatch(ctx context.Context, c *controlConn) {
+	b.watchMu.Lock()
+	defer b.watchMu.Unlock()
+
+	b.mu.Lock()
+	window := b.watched
+	b.mu.Unlock()
+	if window == "" {
+		return
+	}
+
+	lines, err := c.request(ctx, "display-message -p -t "+quote("="+ControlSession+":"+window)+" '#{window_linked_sessions}'")
+	var cmdErr *CommandError
+	switch {
+	case err != nil && (!errors.As(err, &cmdErr) || errors.Is(err, ErrCommandTimedOut)):
+		return
+	case err == nil && (len(lines) != 1 || lines[0] != "1"):
+		return
+	case err == nil:
+		c.request(ctx, "kill-window -t "+window)
+	}
+
+	b.mu.Lock()
+	if b.watched == window {
+		b.dropWatchLocked()
+	}
+	b.mu.Unlock()
+}
+
+// dropWatchLocked forgets the watched window and its live contents;
+// callers must hold b.mu
+func (b *ControlBackend) dropWatchLocked() {
+	for pane := range b.watchedPanes {
+		b.dropCaptureLocked(pane)
+	}
+	b.watched = ""
+	b.watchedPanes = nil
+	b.outputSeq = make(map[string]int)
+}
+
+// dropCaptureLocked drops a pane's cached contents; callers must hold b.mu
+func (b *ControlBackend) dropCaptureLocked(paneID string) {
+	delete(b.captures, captureKey(paneID, true))
+	delete(b.captures, captureKey(paneID, false))
+}
+
+// KillSession kills a running tmux session over the control connection
+func (b *ControlBackend) KillSession(ctx context.Context, name string) error {
+	c := b.connection()
+	if c == nil {
+		return b.fallback.KillSession(ctx, name)
+	}
+	_, err := c.request(ctx, "kill-session -t "+exactTarget(name))
+
+	b.mu.Lock()
+	b.invalidateLocked()
+	b.mu.Unlock()
+	return err
+}
+
+// ListProcesses returns running processes; tmux has no view of these, so
+// this always goes through ps
+func (b *ControlBackend) ListProcesses(ctx context.Context) ([]Process, error) {
+	return b.fallback.ListProcesses(ctx)
+}
+
+// connection returns the live control connection, redialing at most once
+// every controlRedialInterval. Returns nil when tmux can't be reached.
+func (b *ControlBackend) connection() *controlConn {
+	b.dialMu.Lock()
+	defer b.dialMu.Unlock()
+
+	if b.conn != nil && b.conn.alive() {
+		return b.conn
+	}
+	if b.conn != nil {
+		b.conn.close()
+		b.conn = nil
+		b.mu.Lock()
+		b.invalidateLocked()
+		b.dropWatchLocked()
+		b.mu.Unlock()
+	}
+	if time.Since(b.lastDial) < controlRedialInterval {
+		return nil
+	}
+	b.lastDial = time.Now()
+
+	conn, err := dialControl(b.handleNotification)
+	if err != nil {
+		return nil
+	}
+	b.conn = conn
+	return conn
+}
+
+// handleNotification updates the model for a single control-mode notification
+func (b *ControlBackend) handleNotification(line string) {
+	fields := strings.SplitN(line, " ", 3)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch fields[0] {
+	case "%output":
+		if len(fields) > 1 {
+			b.outputSeq[fields[1]]++
+			b.dropCaptureLocked(fields[1])
+		}
+	case "%sessions-changed", "%session-renamed":
+		b.sessionsValid = false
+		b.allPanes.valid = false
+	case "%window-add", "%window-close", "%unlinked-window-add", "%unlinked-window-close",
+		"%layout-change", "%window-pane-changed", "%session-window-changed", "%window-renamed":
+		b.panes = make(map[string]cachedPanes)
+		b.windows = make(map[string]cachedWindows)
+		b.allPanes.valid = false
+		if len(fields) > 1 && fields[1] == b.watched {
+			switch fields[0] {
+			case "%window-close", "%unlinked-window-close":
+				// Never request from the read loop, it would wait for its
+				// own reply
+				go func() {
+					if c := b.connection(); c != nil {
+						b.reconcileWatch(context.Background(), c)
+					}
+				}()
+			case "%layout-change":
+				// Panes may have moved out of the window; watch sees again
+				// which are in it
+				for pane := range b.watchedPanes {
+					b.dropCaptureLocked(pane)
+				}
+				b.watchedPanes = nil
+			}
+		}
+	case "%exit":
+		b.invalidateLocked()
+		b.dropWatchLocked()
+	}
+}
+
+// invalidateLocked drops all cached state; callers must hold b.mu
+func (b *ControlBackend) invalidateLocked() {
+	b.sessionsValid = false
+	b.panes = make(map[string]cachedPanes)
+	b.windows = make(map[string]cachedWindows)
+	b.allPanes.valid = false
+	b.captures = make(map[string]*cachedCapture)
+}
+
+// exactTarget quotes a session name as an exact-match tmux target
+func exactTarget(sessionName string) string {
+	return quote("=" + sessionName)
+}
+
+// quote single-quotes an argument of a control-mode command
+func quote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// controlConn is a running tmux -C client. Commands are written one at a time
+// and their %begin/%end reply blocks are matched in order.
+type controlConn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	reqMu   sync.Mutex // one command in flight at a time
+	replyMu sync.Mutex // guards abandoned and delivery to replies
+	// abandoned counts requests whose caller gave up; their replies are
+	// still on the way and must be dropped to keep replies matched in order
+	abandoned int
+	replies   chan controlReply
+	done      chan struct{}
+	notify    func(line string)
+}
+
+type controlReply struct {
+	lines []string
+	err   error
+}
+
+// dialControl starts a control-mode client attached to ControlSession,
+// creating it with an idle cat rather than a login shell, and waits for it
+// to answer a first command
+func dialControl(notify func(line string)) (*controlConn, error) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return nil, newCommandError("tmux", []string{"-C"}, "", err)
+	}
+
+	// -u as in Run, for the tabs in formats, and -N to never start a server
+	cmd := exec.Command("tmux", "-u", "-N", "-C", "new-session", "-A", "-s", ControlSession, "-f", "ignore-size", "cat")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &controlConn{
+		cmd:     cmd,
+		stdin:   stdin,
+		replies: make(chan controlReply, 1),
+		done:    make(chan struct{}),
+		notify:  notify,
+	}
+	go c.readLoop(stdout)
+
+	if _, err := c.request(context.Background(), "set-option destroy-unattached on"); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// request sends a command and returns its output lines. It gives up when
+// ctx is done or after controlRequestTimeout.
+func (c *controlConn) request(ctx context.Context, command string) ([]string, error) {
+	c.reqMu.Lock()
+	defer c.reqMu.Unlock()
+
+	args := []string{command}
+	if !c.alive() {
+		return nil, newCommandError("tmux", args, "control client exited", nil)
+	}
+	if _, err := io.WriteString(c.stdin, command+"\n"); err != nil {
+		return nil, newCommandError("tmux", args, "", err)
+	}
+
+	timeout := time.NewTimer(controlRequestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case reply := <-c.replies:
+		var cmdErr *CommandError
+		if errors.As(reply.err, &cmdErr) {
+			cmdErr.Args = args
+		}
+		return reply.lines, reply.err
+	case <-c.done:
+		return nil, newCommandError("tmux", args, "control client exited", nil)
+	case <-ctx.Done():
+		c.abandon()
+		return nil, ctx.Err()
+	case <-timeout.C:
+		c.abandon()
+		return nil, newCommandError("tmux", args, "", ErrCommandTimedOut)
+	}
+}
+
+// abandon marks the in-flight request as given up, unless its reply
+// already arrived, in which case the reply is discarded now
+func (c *controlConn) abandon() {
+	c.replyMu.Lock()
+	defer c.replyMu.Unlock()
+
+	select {
+	case <-c.replies:
+	default:
+		c.abandoned++
+	}
+}
+
+// deliver hands a reply to the waiting request, or drops it if that
+// request was abandoned
+func (c *controlConn) deliver(reply controlReply) {
+	c.replyMu.Lock()
+	defer c.replyMu.Unlock()
+
+	if c.abandoned > 0 {
+		c.abandoned--
+		return
+	}
+	select {
+	case c.replies <- reply:
+	default:
+	}
+}
+
+// readLoop parses control-mode output until the client exits
+func (c *controlConn) readLoop(r io.Reader) {
+	defer close(c.done)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var block []string
+	var guard string // "time number" of the open %begin
+	inBlock, fromClient := false, false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if inBlock {
+			isEnd := strings.HasPrefix(line, "%end ")
+			isError := strings.HasPrefix(line, "%error ")
+			if (isEnd || isError) && blockGuard(line) == guard {
+				inBlock = false
+				if fromClient {
+					reply := controlReply{lines: block}
+					if isError {
+						reply.err = newCommandError("tmux", nil, strings.Join(block, "\n"), errors.New("command failed"))
+					}
+					c.deliver(reply)
+				}
+				continue
+			}
+			block = append(block, line)
+			continue
+		}
+
+		if strings.HasPrefix(line, "%begin ") {
+			fields := strings.Fields(line)
+			flags := 0
+			if len(fields) >= 4 {
+				flags, _ = strconv.Atoi(fields[3])
+			}
+			inBlock = true
+			fromClient = flags&1 == 1 // commands sent by this client, not the initial attach
+			guard = blockGuard(line)
+			block = nil
+			continue
+		}
+
+		if strings.HasPrefix(line, "%") && c.notify != nil {
+			c.notify(line)
+		}
+	}
+}
+
+// alive reports whether the client process is still running
+func (c *controlConn) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// close detaches the client and reaps the process
+func (c *controlConn) close() {
+	c.stdin.Close()
+	go c.cmd.Wait()
+}
+
+// blockGuard returns the "time number" pair identifying a reply block
+func blockGuard(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[1] + " " + fields[2]
+}
@@ -0,0 +1,213 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedStdin collects the commands a controlConn writes
+type scriptedStdin struct {
+	commands chan string
+}
+
+func (s scriptedStdin) Write(p []byte) (int, error) {
+	s.commands <- strings.TrimSuffix(string(p), "\n")
+	return len(p), nil
+}
+
+func (s scriptedStdin) Close() error { return nil }
+
+// controlRequest is a request a test makes, in order, and what it expects
+type controlRequest struct {
+	command string
+	timeout time.Duration // gives up after this long when set
+	want    []string
+	wantErr string
+}
+
+func TestControlConnReadLoop(t *testing.T) {
+	// In stdout, "<cmd>" waits for the next command to be written
+	tests := []struct {
+		name          string
+		stdout        []string
+		requests      []controlRequest
+		notifications []string
+	}{
+		{
+			name: "reply between notifications",
+			stdout: []string{
+				// The reply to attaching isn't ours and is skipped
+				"%begin 1700000000 1 0", "%end 1700000000 1 0",
+				"%sessions-changed",
+				"<cmd>",
+				"%window-add @4",
+				"%begin 1700000000 2 1", "api", "%output %1 looks like a notification", "%end 1700000000 2 1",
+				"%layout-change @4 abcd,80x24,0,0,1",
+			},
+			requests: []controlRequest{
+				{command: "list-sessions", want: []string{"api", "%output %1 looks like a notification"}},
+			},
+			notifications: []string{"%sessions-changed", "%window-add @4", "%layout-change @4 abcd,80x24,0,0,1"},
+		},
+		{
+			name: "end line inside a reply",
+			stdout: []string{
+				"<cmd>",
+				"%begin 1700000000 3 1", "%end 1700000000 9 1", "%end 1700000000 3 1",
+			},
+			requests: []controlRequest{
+				{command: "capture-pane -p", want: []string{"%end 1700000000 9 1"}},
+			},
+		},
+		{
+			name: "error reply",
+			stdout: []string{
+				"<cmd>",
+				"%begin 1700000000 4 1", "can't find session: docs", "%error 1700000000 4 1",
+				"<cmd>",
+				"%begin 1700000000 5 1", "web", "%end 1700000000 5 1",
+			},
+			requests: []controlRequest{
+				{command: "kill-session -t '=docs'", wantErr: "tmux kill-session -t '=docs': can't find session: docs"},
+				{command: "list-sessions", want: []string{"web"}},
+			},
+		},
+		{
+			name: "late reply after a timeout",
+			stdout: []string{
+				"<cmd>",
+				"<cmd>",
+				"%begin 1700000000 6 1", "stale", "%end 1700000000 6 1",
+				"%begin 1700000000 7 1", "fresh", "%end 1700000000 7 1",
+			},
+			requests: []controlRequest{
+				{command: "list-sessions", timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded.Error()},
+				{command: "list-panes", want: []string{"fresh"}},
+			},
+		},
+		{
+			name:   "exit",
+			stdout: []string{"<cmd>", "%exit"},
+			requests: []controlRequest{
+				{command: "list-sessions", wantErr: "tmux list-sessions: control client exited"},
+			},
+			notifications: []string{"%exit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var notifications []string
+			stdin := scriptedStdin{commands: make(chan string, len(tt.requests))}
+			c := &controlConn{
+				stdin:   stdin,
+				replies: make(chan controlReply, 1),
+				done:    make(chan struct{}),
+				notify: func(line string) {
+					mu.Lock()
+					notifications = append(notifications, line)
+					mu.Unlock()
+				},
+			}
+			stdout, w := io.Pipe()
+			go c.readLoop(stdout)
+
+			// Play the script, then hang up like an exiting client
+			go func() {
+				defer w.Close()
+				for _, line := range tt.stdout {
+					if line != "<cmd>" {
+						io.WriteString(w, line+"\n")
+						continue
+					}
+					select {
+					case <-stdin.commands:
+					case <-time.After(5 * time.Second):
+						t.Error("no command written")
+						return
+					}
+				}
+			}()
+
+			for _, req := range tt.requests {
+				ctx := context.Background()
+				if req.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, req.timeout)
+					defer cancel()
+				}
+				lines, err := c.request(ctx, req.command)
+				switch {
+				case req.wantErr != "":
+					if err == nil || err.Error() != req.wantErr {
+						t.Errorf("%s: err = %v, want %q", req.command, err, req.wantErr)
+					}
+				case err != nil:
+					t.Errorf("%s: %v", req.command, err)
+				case !reflect.DeepEqual(lines, req.want):
+					t.Errorf("%s: got %q, want %q", req.command, lines, req.want)
+				}
+			}
+
+			select {
+			case <-c.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("readLoop didn't return at the end of its input")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(notifications, tt.notifications) {
+				t.Errorf("notifications %q, want %q", notifications, tt.notifications)
+			}
+		})
+	}
+}
+
+func TestControlConnErrorKind(t *testing.T) {
+	stdin := scriptedStdin{commands: make(chan string, 1)}
+	c := &controlConn{stdin: stdin, replies: make(chan controlReply, 1), done: make(chan struct{})}
+	stdout, w := io.Pipe()
+	go c.readLoop(stdout)
+	go func() {
+		<-stdin.commands
+		io.WriteString(w, "%begin 1 1 1\ncan't find session: docs\n%error 1 1 1\n")
+		w.Close()
+	}()
+
+	_, err := c.request(context.Background(), "kill-session -t '=docs'")
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestControlBackendOutputInvalidatesCapture(t *testing.T) {
+	b := NewControlBackend()
+	b.watched = "@1"
+	b.watchedPanes = map[string]bool{"%1": true, "%2": true}
+	old := time.Now().Add(-time.Hour)
+	b.captures[captureKey("%1", true)] = &cachedCapture{height: 5, text: "a", at: old, live: true}
+	b.captures[captureKey("%2", true)] = &cachedCapture{height: 5, text: "b", at: old, live: true}
+
+	b.handleNotification("%output %1 hello\\015\\012")
+	if _, ok := b.captures[captureKey("%1", true)]; ok {
+		t.Error("%1 still cached after its output")
+	}
+	if _, ok := b.captures[captureKey("%2", true)]; !ok {
+		t.Error("%2 dropped on output of %1")
+	}
+	if b.outputSeq["%1"] != 1 {
+		t.Errorf("output seq of %%1 = %d, want 1", b.outputSeq["%1"])
+	}
+
+	b.handleNotification("%layout-change @1 abcd,80x24,0,0,1")
+	if len(b.captures) != 0 || b.watchedPanes != nil || b.watched != "@1" {
+		t.Errorf("after layout change: %d captures, panes %v, window %q", len(b.captures), b.watchedPanes, b.watched)
+	}
+}
@@ -0,0 +1,292 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecBackend implements Backend by running the tmux and ps binaries
+type ExecBackend struct{}
+
+// NewExecBackend returns a Backend that talks to the local tmux server
+func NewExecBackend() *ExecBackend {
+	return &ExecBackend{}
+}
+
+// ListSessions returns list of currently running tmux sessions
+func (b *ExecBackend) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	output, err := Run(ctx, "tmux", "list-sessions", "-F", sessionFormat)
+	if err != nil {
+		return nil, err
+	}
+	sessions := parseSessionLines(strings.Split(string(output), "\n"))
+
+	// Best-effort: without the client list, control clients count as attached
+	if clients, err := Run(ctx, "tmux", "list-clients", "-F", clientFormat); err == nil {
+		discountControlClients(sessions, strings.Split(string(clients), "\n"))
+	}
+	return sessions, nil
+}
+
+// clientFormat is the list-clients format read by discountControlClients
+const clientFormat = "#{client_session}\t#{client_control_mode}"
+
+// discountControlClients removes control-mode clients, such as warpp's own,
+// from the attached counts so they only count terminals
+func discountControlClients(sessions []SessionInfo, clientLines []string) {
+	control := make(map[string]int)
+	for _, line := range clientLines {
+		fields := strings.Split(line, "\t")
+		if len(fields) == 2 && fields[1] == "1" {
+			control[fields[0]]++
+		}
+	}
+	for i := range sessions {
+		sessions[i].Attached = max(sessions[i].Attached-control[sessions[i].Name], 0)
+	}
+}
+
+// sessionFormat is the list-sessions format read by parseSessionLines
+const sessionFormat = "#{session_name}\t#{session_created}\t#{session_activity}\t#{session_attached}\t#{session_windows}\t#{session_alerts}\t#{session_path}"
+
+// parseSessionLines parses list-sessions output in sessionFormat, leaving
+// out ControlSession
+func parseSessionLines(lines []string) []SessionInfo {
+	sessions := []SessionInfo{}
+	for _, line := range lines {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 7 || fields[0] == "" || fields[0] == ControlSession {
+			continue
+		}
+		created, _ := strconv.ParseInt(fields[1], 10, 64)
+		activity, _ := strconv.ParseInt(fields[2], 10, 64)
+		attached, _ := strconv.Atoi(fields[3])
+		windows, _ := strconv.Atoi(fields[4])
+		sessions = append(sessions, SessionInfo{
+			Name:     fields[0],
+			Created:  time.Unix(created, 0),
+			Activity: time.Unix(activity, 0),
+			Attached: attached,
+			Windows:  windows,
+			Alerts:   fields[5],
+			Path:     fields[6],
+		})
+	}
+	return sessions
+}
+
+// ListPanes returns all panes in the active window of a session
+func (b *ExecBackend) ListPanes(ctx context.Context, sessionName string) ([]PaneInfo, error) {
+	// List all panes in the session's current window
+	output, err := Run(ctx, "tmux", "list-panes", "-t", "="+sessionName, "-F", paneFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []PaneInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if pane, ok := parsePaneLine(sessionName, line); ok {
+			panes = append(panes, pane)
+		}
+	}
+
+	return panes, nil
+}
+
+// paneFormat is the list-panes format read by parsePaneLine
+const paneFormat = "#{pane_index} #{pane_id} #{pane_left} #{pane_top} #{pane_width} #{pane_height} #{pane_active}"
+
+// parsePaneLine parses one line of list-panes output in paneFormat
+func parsePaneLine(sessionName, line string) (PaneInfo, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return PaneInfo{}, false
+	}
+	pane := PaneInfo{Session: sessionName, ID: fields[1], Active: fields[6] == "1"}
+	pane.Index, _ = strconv.Atoi(fields[0])
+	pane.Left, _ = strconv.Atoi(fields[2])
+	pane.Top, _ = strconv.Atoi(fields[3])
+	pane.Width, _ = strconv.Atoi(fields[4])
+	pane.Height, _ = strconv.Atoi(fields[5])
+	return pane, true
+}
+
+// windowFormat is the list-panes -s format read by parseWindowLines. Fields
+// are tab-separated since names and paths may contain spaces.
+const windowFormat = "#{window_index}\t#{window_name}\t#{window_active}\t#{window_layout}\t#{pane_index}\t#{pane_id}\t#{pane_active}\t#{pane_pid}\t#{pane_current_command}\t#{pane_current_path}"
+
+// parseWindowLines groups list-panes -s output in windowFormat into windows
+func parseWindowLines(sessionName string, lines []string) []WindowInfo {
+	var windows []WindowInfo
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 10 {
+			continue
+		}
+		windowIdx, _ := strconv.Atoi(fields[0])
+		if len(windows) == 0 || windows[len(windows)-1].Index != windowIdx {
+			windows = append(windows, WindowInfo{
+				Session: sessionName,
+				Index:   windowIdx,
+				Name:    fields[1],
+				Active:  fields[2] == "1",
+				Layout:  fields[3],
+			})
+		}
+		paneIdx, _ := strconv.Atoi(fields[4])
+		pid, _ := strconv.Atoi(fields[7])
+		w := &windows[len(windows)-1]
+		w.Panes = append(w.Panes, PaneInfo{
+			Session: sessionName,
+			Index:   paneIdx,
+			ID:      fields[5],
+			Active:  fields[6] == "1",
+			PID:     pid,
+			Command: fields[8],
+			Path:    fields[9],
+		})
+	}
+	return windows
+}
+
+// ListWindows returns every window of a session with its panes
+func (b *ExecBackend) ListWindows(ctx context.Context, sessionName string) ([]WindowInfo, error) {
+	output, err := Run(ctx, "tmux", "list-panes", "-s", "-t", "="+sessionName, "-F", windowFormat)
+	if err != nil {
+		return nil, err
+	}
+	return parseWindowLines(sessionName, strings.Split(strings.TrimSpace(string(output)), "\n")), nil
+}
+
+// ListAllPanes returns every pane on the server with its session and TTY
+func (b *ExecBackend) ListAllPanes(ctx context.Context) ([]PaneInfo, error) {
+	output, err := Run(ctx, "tmux", "list-panes", "-a", "-F", allPaneFormat)
+	if err != nil {
+		return nil, err
+	}
+	return parseAllPaneLines(strings.Split(string(output), "\n")), nil
+}
+
+// allPaneFormat is the list-panes -a format read by parseAllPaneLines. The
+// session name goes last since it may contain spaces; an unset agent state
+// is printed as - to keep the field.
+const allPaneFormat = "#{pane_id} #{pane_tty} #{pane_index} #{pane_pid} #{?" + AgentStateOption + ",#{" + AgentStateOption + "},-} #{session_name}"
+
+// parseAllPaneLines parses list-panes -a output in allPaneFormat, leaving
+// out the panes of ControlSession
+func parseAllPaneLines(lines []string) []PaneInfo {
+	var panes []PaneInfo
+	for _, line := range lines {
+		fields := strings.SplitN(line, " ", 6)
+		if len(fields) < 6 || fields[5] == ControlSession {
+			continue
+		}
+		idx, _ := strconv.Atoi(fields[2])
+		pid, _ := strconv.Atoi(fields[3])
+		hookState := fields[4]
+		if hookState == "-" {
+			hookState = ""
+		}
+		panes = append(panes, PaneInfo{
+			Session:   fields[5],
+			ID:        fields[0],
+			TTY:       fields[1], // e.g. /dev/pts/3 or /dev/ttys044
+			Index:     idx,
+			PID:       pid,
+			HookState: hookState,
+		})
+	}
+	return panes
+}
+
+// paneTarget is an exact-match target for a pane in the active window of a
+// session, e.g. =web:.1, or for its active pane with ActivePane, =web:
+func paneTarget(sessionName string, paneIndex int) string {
+	if paneIndex == ActivePane {
+		return "=" + sessionName + ":"
+	}
+	return fmt.Sprintf("=%s:.%d", sessionName, paneIndex)
+}
+
+// CapturePane captures content from a specific pane
+func (b *ExecBackend) CapturePane(ctx context.Context, sessionName string, paneIndex int, height int, ansi bool) (string, error) {
+	args := []string{"capture-pane", "-t", paneTarget(sessionName, paneIndex), "-p"}
+	if ansi {
+		// Use -e to preserve ANSI escape sequences (colors)
+		args = append(args, "-e")
+	}
+	args = append(args, "-S", fmt.Sprintf("-%d", height))
+
+	output, err := Run(ctx, "tmux", args...)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// CapturePaneByID captures content from a pane by its id, e.g. %3
+func (b *ExecBackend) CapturePaneByID(ctx context.Context, paneID string, height int, ansi bool) (string, error) {
+	args := []string{"capture-pane", "-t", paneID, "-p"}
+	if ansi {
+		args = append(args, "-e")
+	}
+	args = append(args, "-S", fmt.Sprintf("-%d", height))
+
+	output, err := Run(ctx, "tmux", args...)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// KillSession kills a running tmux session
+func (b *ExecBackend) KillSession(ctx context.Context, name string) error {
+	_, err := Run(ctx, "tmux", "kill-session", "-t", "="+name)
+	return err
+}
+
+// ListProcesses returns all processes with their parents and controlling
+// TTYs, read from /proc on Linux and from ps elsewhere
+func (b *ExecBackend) ListProcesses(ctx context.Context) ([]Process, error) {
+	return listProcesses(ctx)
+}
+
+// listProcessesPS reads the process table with ps
+func listProcessesPS(ctx context.Context) ([]Process, error) {
+	output, err := Run(ctx, "ps", "-Ao", "pid=,ppid=,tty=,args=")
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[0])
+		ppid, _ := strconv.Atoi(fields[1])
+		procs = append(procs, Process{
+			PID:     pid,
+			PPID:    ppid,
+			TTY:     psTTY(fields[2]),
+			Command: strings.Join(fields[3:], " "),
+		})
+	}
+	return procs, nil
+}
+
+// psTTY expands the TTY column of ps to a device path: pts/3 on Linux is
+// /dev/pts/3 and s044 on macOS is /dev/ttys044. ? means no terminal.
+func psTTY(tty string) string {
+	switch {
+	case tty == "?" || tty == "??" || tty == "-":
+		return ""
+	case strings.HasPrefix(tty, "pts/") || strings.HasPrefix(tty, "tty"):
+		return "/dev/" + tty
+	}
+	return "/dev/tty" + tty
+}
@@ -0,0 +1,279 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ansiEscape matches CSI escape sequences so plain captures can drop them
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// FakeBackend is an in-memory Backend for tests and demos. Sessions, panes,
+// pane contents and processes are scripted through its setters.
+type FakeBackend struct {
+	mu        sync.Mutex
+	sessions  []fakeSession
+	processes []Process
+}
+
+type fakeSession struct {
+	name  string
+	panes []PaneInfo
+	info  SessionInfo
+}
+
+// NewFakeBackend returns an empty in-memory backend
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{}
+}
+
+// AddSession adds a running session with the given panes. Panes without an ID,
+// TTY or PID get unique ones so processes can be attached to them.
+func (f *FakeBackend) AddSession(name string, panes ...PaneInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(panes) == 0 {
+		panes = []PaneInfo{{Index: 0}}
+	}
+	base := f.paneCount()
+	active := false
+	for _, p := range panes {
+		active = active || p.Active
+	}
+	if !active {
+		panes[0].Active = true
+	}
+	for i := range panes {
+		panes[i].Session = name
+		if panes[i].ID == "" {
+			panes[i].ID = fmt.Sprintf("%%%d", base+i)
+		}
+		if panes[i].TTY == "" {
+			panes[i].TTY = fmt.Sprintf("/dev/ttys%03d", base+i)
+		}
+		if panes[i].PID == 0 {
+			panes[i].PID = 100 + base + i
+		}
+	}
+	now := time.Now()
+	info := SessionInfo{Name: name, Created: now, Activity: now, Windows: 1}
+	f.sessions = append(f.sessions, fakeSession{name: name, panes: panes, info: info})
+}
+
+// SetSessionInfo replaces the metadata of a session; the name is kept
+func (f *FakeBackend) SetSessionInfo(sessionName string, info SessionInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.sessions {
+		if f.sessions[i].name == sessionName {
+			info.Name = sessionName
+			f.sessions[i].info = info
+		}
+	}
+}
+
+// SetPaneContent replaces the captured content of a pane
+func (f *FakeBackend) SetPaneContent(sessionName string, paneIndex int, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if p := f.pane(sessionName, paneIndex); p != nil {
+		p.Content = content
+	}
+}
+
+// AddProcess registers a process running on the TTY of the given pane
+func (f *FakeBackend) AddProcess(sessionName string, paneIndex int, command string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.pane(sessionName, paneIndex)
+	if p == nil {
+		return
+	}
+	f.processes = append(f.processes, Process{
+		PID:     1000 + len(f.processes),
+		PPID:    p.PID,
+		TTY:     p.TTY,
+		Command: command,
+	})
+}
+
+// ListSessions returns all fake sessions
+func (f *FakeBackend) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.sessions) == 0 {
+		return nil, fakeError(ErrNoServer, "list-sessions", "no server running")
+	}
+	sessions := []SessionInfo{}
+	for _, s := range f.sessions {
+		sessions = append(sessions, s.info)
+	}
+	return sessions, nil
+}
+
+// ListPanes returns copies of a session's panes
+func (f *FakeBackend) ListPanes(ctx context.Context, sessionName string) ([]PaneInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range f.sessions {
+		if s.name == sessionName {
+			return append([]PaneInfo(nil), s.panes...), nil
+		}
+	}
+	return nil, fakeError(ErrSessionNotFound, "list-panes", "can't find session: "+sessionName)
+}
+
+// ListWindows returns a session's panes as a single window
+func (f *FakeBackend) ListWindows(ctx context.Context, sessionName string) ([]WindowInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range f.sessions {
+		if s.name == sessionName {
+			return []WindowInfo{{
+				Session: sessionName,
+				Name:    "main",
+				Active:  true,
+				Panes:   append([]PaneInfo(nil), s.panes...),
+			}}, nil
+		}
+	}
+	return nil, fakeError(ErrSessionNotFound, "list-panes", "can't find session: "+sessionName)
+}
+
+// ListAllPanes returns copies of every pane of every session
+func (f *FakeBackend) ListAllPanes(ctx context.Context) ([]PaneInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var panes []PaneInfo
+	for _, s := range f.sessions {
+		panes = append(panes, s.panes...)
+	}
+	return panes, nil
+}
+
+// CapturePane returns the last height lines of a pane's scripted content
+func (f *FakeBackend) CapturePane(ctx context.Context, sessionName string, paneIndex int, height int, ansi bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if paneIndex == ActivePane {
+		paneIndex = 0
+		for _, s := range f.sessions {
+			for _, p := range s.panes {
+				if s.name == sessionName && p.Active {
+					paneIndex = p.Index
+				}
+			}
+		}
+	}
+
+	p := f.pane(sessionName, paneIndex)
+	if p == nil {
+		return "", fakeError(ErrSessionNotFound, "capture-pane", "can't find pane: "+sessionName)
+	}
+
+	content := p.Content
+	if !ansi {
+		content = ansiEscape.ReplaceAllString(content, "")
+	}
+	lines := strings.Split(content, "\n")
+	if height > 0 && len(lines) > height {
+		lines = lines[len(lines)-height:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// CapturePaneByID returns the last height lines of a pane's scripted content
+func (f *FakeBackend) CapturePaneByID(ctx context.Context, paneID string, height int, ansi bool) (string, error) {
+	f.mu.Lock()
+	var session string
+	index := -1
+	for _, s := range f.sessions {
+		for _, p := range s.panes {
+			if p.ID == paneID {
+				session, index = s.name, p.Index
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if index < 0 {
+		return "", fakeError(ErrSessionNotFound, "capture-pane", "can't find pane: "+paneID)
+	}
+	return f.CapturePane(ctx, session, index, height, ansi)
+}
+
+// KillSession removes a session and the processes running in its panes
+func (f *FakeBackend) KillSession(ctx context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, s := range f.sessions {
+		if s.name != name {
+			continue
+		}
+		ttys := make(map[string]bool)
+		for _, p := range s.panes {
+			ttys[p.TTY] = true
+		}
+		var procs []Process
+		for _, proc := range f.processes {
+			if !ttys[proc.TTY] {
+				procs = append(procs, proc)
+			}
+		}
+		f.processes = procs
+		f.sessions = append(f.sessions[:i], f.sessions[i+1:]...)
+		return nil
+	}
+	return fakeError(ErrSessionNotFound, "kill-session", "can't find session: "+name)
+}
+
+// ListProcesses returns copies of all scripted processes
+func (f *FakeBackend) ListProcesses(ctx context.Context) ([]Process, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Process(nil), f.processes...), nil
+}
+
+// pane returns a pointer to a pane; callers must hold f.mu
+func (f *FakeBackend) pane(sessionName string, paneIndex int) *PaneInfo {
+	for i := range f.sessions {
+		if f.sessions[i].name != sessionName {
+			continue
+		}
+		for j := range f.sessions[i].panes {
+			if f.sessions[i].panes[j].Index == paneIndex {
+				return &f.sessions[i].panes[j]
+			}
+		}
+	}
+	return nil
+}
+
+// paneCount returns the number of panes across all sessions; callers must hold f.mu
+func (f *FakeBackend) paneCount() int {
+	n := 0
+	for _, s := range f.sessions {
+		n += len(s.panes)
+	}
+	return n
+}
+
+// fakeError builds the same error a real tmux failure would produce
+func fakeError(kind error, command string, stderr string) error {
+	return &CommandError{Name: "tmux", Args: []string{command}, Stderr: stderr, Kind: kind, Err: kind}
+}
@@ -0,0 +1,168 @@
+package tmux
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// tmux's default-size; absolute split sizes in specs are scaled against it
+const (
+	defaultWindowWidth  = 80
+	defaultWindowHeight = 24
+)
+
+// Rect is a pane's position within its window, as fractions of the window size
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Geometry simulates the window's splits and returns one Rect per pane, in
+// pane order. Named tmux layouts are approximated.
+func (w SpecWindow) Geometry() []Rect {
+	panes := w.Panes
+	if len(panes) == 0 {
+		panes = []SpecPane{{}}
+	}
+
+	rects := []Rect{{X: 0, Y: 0, W: 1, H: 1}}
+	for i, pane := range panes[1:] {
+		target := i // the previous pane
+		if pane.Target != nil && *pane.Target < len(rects) {
+			target = *pane.Target
+		}
+		r := rects[target]
+
+		if flag, _ := splitFlag(pane.Split); flag == "-h" {
+			// New pane takes the right part of the target
+			newW := r.W * splitFraction(pane.Size, defaultWindowWidth)
+			rects[target].W = r.W - newW
+			rects = append(rects, Rect{X: r.X + r.W - newW, Y: r.Y, W: newW, H: r.H})
+		} else {
+			// New pane takes the bottom part of the target
+			newH := r.H * splitFraction(pane.Size, defaultWindowHeight)
+			rects[target].H = r.H - newH
+			rects = append(rects, Rect{X: r.X, Y: r.Y + r.H - newH, W: r.W, H: newH})
+		}
+	}
+
+	if preset := presetGeometry(w.Layout, len(rects)); preset != nil {
+		return preset
+	}
+	return rects
+}
+
+// splitFraction converts a spec size to the fraction of the target pane the
+// new pane takes; the default is half
+func splitFraction(size string, cells int) float64 {
+	if size == "" {
+		return 0.5
+	}
+
+	fraction := 0.5
+	if pct, ok := strings.CutSuffix(size, "%"); ok {
+		if n, err := strconv.Atoi(pct); err == nil {
+			fraction = float64(n) / 100
+		}
+	} else if n, err := strconv.Atoi(size); err == nil {
+		fraction = float64(n) / float64(cells)
+	}
+	return math.Min(math.Max(fraction, 0.1), 0.9)
+}
+
+// presetGeometry lays out n panes like tmux's named layouts, or returns nil
+// for unknown or custom layout strings
+func presetGeometry(layout string, n int) []Rect {
+	rects := make([]Rect, n)
+	switch layout {
+	case "even-horizontal":
+		for i := range rects {
+			rects[i] = Rect{X: float64(i) / float64(n), W: 1 / float64(n), H: 1}
+		}
+	case "even-vertical":
+		for i := range rects {
+			rects[i] = Rect{Y: float64(i) / float64(n), W: 1, H: 1 / float64(n)}
+		}
+	case "main-vertical", "main-horizontal":
+		if n == 1 {
+			return []Rect{{W: 1, H: 1}}
+		}
+		rest := float64(n - 1)
+		for i := range rects {
+			switch {
+			case layout == "main-vertical" && i == 0:
+				rects[i] = Rect{W: 0.5, H: 1}
+			case layout == "main-vertical":
+				rects[i] = Rect{X: 0.5, Y: float64(i-1) / rest, W: 0.5, H: 1 / rest}
+			case i == 0:
+				rects[i] = Rect{W: 1, H: 0.5}
+			default:
+				rects[i] = Rect{X: float64(i-1) / rest, Y: 0.5, W: 1 / rest, H: 0.5}
+			}
+		}
+	case "tiled":
+		cols := int(math.Ceil(math.Sqrt(float64(n))))
+		rows := int(math.Ceil(float64(n) / float64(cols)))
+		for i := range rects {
+			row, col := i/cols, i%cols
+			// The last row stretches to fill the width
+			rowCols := cols
+			if row == rows-1 && n%cols != 0 {
+				rowCols = n % cols
+			}
+			rects[i] = Rect{
+				X: float64(col) / float64(rowCols),
+				Y: float64(row) / float64(rows),
+				W: 1 / float64(rowCols),
+				H: 1 / float64(rows),
+			}
+		}
+	default:
+		return nil
+	}
+	return rects
+}
+
+// PaneGeometry returns one Rect per live pane from its tmux position and
+// size. Each rect includes the border cell on its left and top, so panes
+// that share a border share an edge.
+func PaneGeometry(panes []PaneInfo) []Rect {
+	width, height := 0, 0
+	for _, p := range panes {
+		width = max(width, p.Left+p.Width)
+		height = max(height, p.Top+p.Height)
+	}
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	// The window plus its outer border is width+2 cells, i.e. width+1 steps
+	w, h := float64(width+1), float64(height+1)
+	rects := make([]Rect, len(panes))
+	for i, p := range panes {
+		rects[i] = Rect{
+			X: float64(p.Left) / w,
+			Y: float64(p.Top) / h,
+			W: float64(p.Width+1) / w,
+			H: float64(p.Height+1) / h,
+		}
+	}
+	return rects
+}
+
+// PaneRoot returns the resolved working directory of a pane, given the
+// session root it is started with ("" for the spec's own)
+func (s *Spec) PaneRoot(root string, window, pane int) string {
+	if root == "" {
+		root = s.Root
+	}
+	dir := expandPath(root)
+	if window < len(s.Windows) {
+		win := s.Windows[window]
+		dir = joinRoot(dir, win.Root)
+		if pane < len(win.Panes) {
+			dir = joinRoot(dir, win.Panes[pane].Root)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,93 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// listProcesses reads the process table from /proc, falling back to ps when
+// /proc isn't mounted
+func listProcesses(ctx context.Context) ([]Process, error) {
+	procs, err := scanProc("/proc")
+	if err != nil {
+		return listProcessesPS(ctx)
+	}
+	return procs, nil
+}
+
+// scanProc reads each process's parent, controlling TTY and command line
+// from /proc/<pid>/stat and /proc/<pid>/cmdline. Processes that exit while
+// scanning are skipped.
+func scanProc(root string) ([]Process, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(root, e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		proc, ok := parseProcStat(pid, string(stat))
+		if !ok {
+			continue
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(root, e.Name(), "cmdline")); err == nil && len(cmdline) > 0 {
+			proc.Command = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+		}
+		procs = append(procs, proc)
+	}
+	return procs, nil
+}
+
+// parseProcStat parses /proc/<pid>/stat: "pid (comm) state ppid pgrp session
+// tty_nr ...". comm may itself contain spaces and parentheses, so fields are
+// read after its last ')'. Command is set to comm in brackets, as ps shows
+// processes without a command line.
+func parseProcStat(pid int, stat string) (Process, bool) {
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return Process{}, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 5 {
+		return Process{}, false
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	ttyNr, _ := strconv.ParseInt(fields[4], 10, 64)
+	return Process{
+		PID:     pid,
+		PPID:    ppid,
+		TTY:     ttyName(ttyNr),
+		Command: "[" + stat[open+1:end] + "]",
+	}, true
+}
+
+// ttyName maps a kernel tty_nr to its device path, e.g. /dev/pts/3. It
+// returns "" for no controlling terminal or a device warpp doesn't know.
+func ttyName(ttyNr int64) string {
+	if ttyNr == 0 {
+		return ""
+	}
+	major := (ttyNr >> 8) & 0xfff
+	minor := (ttyNr & 0xff) | ((ttyNr >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		// Unix98 pseudo-terminals span majors 136-143, 256 minors each
+		return fmt.Sprintf("/dev/pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("/dev/tty%d", minor)
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name string
+		stat string
+		want Process
+		ok   bool
+	}{
+		{
+			name: "shell on a pty",
+			stat: "4242 (zsh) S 4200 4242 4242 34819 4300 4194560 2500 0 0 0",
+			want: Process{PID: 4242, PPID: 4200, TTY: "/dev/pts/3", Command: "[zsh]"},
+			ok:   true,
+		},
+		{
+			name: "comm with spaces and parentheses",
+			stat: "4242 (tmux: server (1)) S 1 4242 4242 0 -1 4194368",
+			want: Process{PID: 4242, PPID: 1, Command: "[tmux: server (1)]"},
+			ok:   true,
+		},
+		{
+			name: "pty above 255",
+			stat: "4242 (node) R 4200 4242 4242 35116 4300 0",
+			want: Process{PID: 4242, PPID: 4200, TTY: "/dev/pts/300", Command: "[node]"},
+			ok:   true,
+		},
+		{
+			name: "virtual console",
+			stat: "4242 (login) S 1 4242 4242 1025 4242 0",
+			want: Process{PID: 4242, PPID: 1, TTY: "/dev/tty1", Command: "[login]"},
+			ok:   true,
+		},
+		{name: "truncated", stat: "4242 (zsh) S 4200", ok: false},
+		{name: "no comm", stat: "4242 zsh S 4200 4242 4242 34819", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProcStat(4242, tt.stat)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanProc(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"100/stat":    "100 (zsh) S 1 100 100 34816 0 0",
+		"100/cmdline": "-zsh\x00",
+		"200/stat":    "200 (claude) S 100 200 100 34816 0 0",
+		"200/cmdline": "node\x00/usr/local/bin/claude\x00--continue\x00",
+		"300/stat":    "300 (kworker/0:1) I 2 0 0 0 -1 0",
+		"300/cmdline": "",
+		"400/cmdline": "exited before its stat was read\x00",
+		"self/stat":   "1 (init) S 0 1 1 0 -1 0",
+		"uptime":      "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	procs, err := scanProc(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	want := []Process{
+		{PID: 100, PPID: 1, TTY: "/dev/pts/0", Command: "-zsh"},
+		{PID: 200, PPID: 100, TTY: "/dev/pts/0", Command: "node /usr/local/bin/claude --continue"},
+		{PID: 300, PPID: 2, Command: "[kworker/0:1]"},
+	}
+	if !reflect.DeepEqual(procs, want) {
+		t.Errorf("got  %+v\nwant %+v", procs, want)
+	}
+}
+
+func TestProcessPanes(t *testing.T) {
+	panes := []PaneInfo{
+		{Session: "web", ID: "%1", TTY: "/dev/pts/1", PID: 100},
+		{Session: "api", ID: "%2", TTY: "/dev/pts/2", PID: 200},
+	}
+	procs := []Process{
+		{PID: 100, PPID: 1, TTY: "/dev/pts/1", Command: "-zsh"},
+		// claude under node under the shell of %1
+		{PID: 110, PPID: 100, TTY: "/dev/pts/1", Command: "node"},
+		{PID: 111, PPID: 110, TTY: "/dev/pts/1", Command: "claude"},
+		// Reparented to init but still on the pane's terminal
+		{PID: 300, PPID: 1, TTY: "/dev/pts/2", Command: "claude"},
+		// A descendant of %2 that opened another terminal
+		{PID: 210, PPID: 200, TTY: "/dev/pts/9", Command: "ssh"},
+		// Neither a descendant nor on a pane's terminal
+		{PID: 400, PPID: 1, TTY: "/dev/pts/7", Command: "claude"},
+		{PID: 500, PPID: 2, Command: "[kworker/0:1]"},
+	}
+
+	got := make(map[int]string)
+	for pid, pane := range processPanes(procs, panes) {
+		got[pid] = pane.ID
+	}
+	want := map[int]string{100: "%1", 110: "%1", 111: "%1", 300: "%2", 210: "%2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tmux
+
+import "context"
+
+// listProcesses reads the process table with ps
+func listProcesses(ctx context.Context) ([]Process, error) {
+	return listProcessesPS(ctx)
+}
@@ -0,0 +1,145 @@
+package tmux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Error kinds carried by CommandError, for use with errors.Is
+var (
+	ErrNotInstalled    = errors.New("not installed")
+	ErrNoServer        = errors.New("no tmux server running")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrTmuxifierFailed = errors.New("tmuxifier failed")
+	ErrCommandTimedOut = errors.New("command timed out")
+)
+
+// commandTimeouts bounds each external command by binary; anything else
+// gets defaultCommandTimeout
+var commandTimeouts = map[string]time.Duration{
+	"tmux":      3 * time.Second,
+	"ps":        3 * time.Second,
+	"git":       30 * time.Second,
+	"tmuxifier": 30 * time.Second,
+}
+
+const defaultCommandTimeout = 10 * time.Second
+
+// CommandError describes a failed external command
+type CommandError struct {
+	Name   string   // binary, e.g. tmux
+	Args   []string // arguments passed to it
+	Stderr string   // captured stderr, trimmed
+	Kind   error    // one of the Err* kinds above, or nil if unclassified
+	Err    error    // underlying error from exec or the control connection
+}
+
+func (e *CommandError) Error() string {
+	if errors.Is(e.Kind, ErrNotInstalled) {
+		return fmt.Sprintf("%s is not installed or not in PATH", e.Name)
+	}
+
+	command := e.Name
+	if len(e.Args) > 0 {
+		command += " " + e.Args[0]
+	}
+	if errors.Is(e.Kind, ErrCommandTimedOut) {
+		return fmt.Sprintf("%s timed out", command)
+	}
+	if e.Stderr != "" {
+		return fmt.Sprintf("%s: %s", command, firstLine(e.Stderr))
+	}
+	if e.Kind != nil {
+		return fmt.Sprintf("%s: %s", command, e.Kind)
+	}
+	return fmt.Sprintf("%s: %v", command, e.Err)
+}
+
+// Unwrap exposes both the error kind and the underlying error to errors.Is
+func (e *CommandError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// Run runs an external command with a per-binary timeout and returns its
+// stdout. Failures are returned as *CommandError with stderr attached.
+func Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return RunEnv(ctx, nil, name, args...)
+}
+
+// RunEnv is Run with environment variables added to warpp's own, e.g.
+// SESSION_ROOT=/src
+func RunEnv(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	timeout, ok := commandTimeouts[name]
+	if !ok {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Without a UTF-8 locale (or $TMUX) tmux replaces the tabs separating
+	// format fields with _; -u marks the client as UTF-8
+	execArgs := args
+	if name == "tmux" {
+		execArgs = append([]string{"-u"}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, execArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ErrCommandTimedOut
+	}
+	return stdout.Bytes(), newCommandError(name, args, stderr.String(), err)
+}
+
+// newCommandError classifies a failure by its error and stderr output
+func newCommandError(name string, args []string, stderr string, err error) *CommandError {
+	stderr = strings.TrimSpace(stderr)
+	e := &CommandError{Name: name, Args: args, Stderr: stderr, Err: err}
+
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		e.Kind = ErrNotInstalled
+	case errors.Is(err, ErrCommandTimedOut):
+		e.Kind = ErrCommandTimedOut
+	case name == "tmuxifier":
+		e.Kind = ErrTmuxifierFailed
+	case strings.Contains(stderr, "no server running"),
+		strings.Contains(stderr, "error connecting to"):
+		e.Kind = ErrNoServer
+	case strings.Contains(stderr, "can't find session"),
+		strings.Contains(stderr, "session not found"):
+		e.Kind = ErrSessionNotFound
+	}
+	return e
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
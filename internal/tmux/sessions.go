@@ -1,84 +1,84 @@
 package tmux
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
 )
 
 type Session struct {
 	Name         string
 	Description  string
 	IsRunning    bool
-	IsLayout     bool   // true if this is a layout entry (not a running session)
-	ProjectRoot  string // parsed from session_root in layout file
-	ProjectType  string // detected from layout or directory
-	Icon         string // emoji for project type
-	ClaudeStatus string // "executing", "idle", or "" (no Claude)
+	IsLayout     bool         // true if this is a layout entry (not a running session)
+	ProjectRoot  string       // parsed from session_root in layout file
+	ProjectType  string       // detected from layout or directory
+	Icon         string       // emoji for project type
+	Agents       []AgentState // coding agents running in its panes, in pane order
+	Source       string       // SourceTmuxifier or SourceWarpp for layouts, "" for orphan sessions
+	LayoutPath   string       // file the layout was loaded from
+	Layout       *Spec        // parsed windows and panes of the layout, nil if it couldn't be read
+	LayoutIssues []string     // parse warnings and validation errors for the layout
+	// Running sessions only
+	Created  time.Time
+	Activity time.Time // last activity in any pane
+	Attached int       // attached clients
+	Windows  int
+	Alerts   string // windows with bell, activity or silence flags
 }
 
 // GetAllSessions returns running sessions (first) + layouts (second) as separate entries
-func GetAllSessions() ([]Session, error) {
+func GetAllSessions(ctx context.Context, b Backend) ([]Session, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
 
-	layoutsDir := filepath.Join(home, ".tmuxifier", "layouts")
-	entries, err := os.ReadDir(layoutsDir)
+	layoutsByName, err := getLayouts(home)
 	if err != nil {
 		return nil, err
 	}
 
 	var runningSessions []Session
 	var layouts []Session
-	runningNames := getRunningTmuxSessions()
-	layoutNames := make(map[string]bool)
-	claudeStatus := GetClaudeSessionStatus() // Get claude status for all sessions at once
-
-	// First, collect all tmuxifier layouts
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".session.sh") || e.Name() == "new-session.session.sh" {
-			continue
-		}
-
-		name := strings.TrimSuffix(e.Name(), ".session.sh")
-		if name == "" {
-			continue
-		}
+	running, err := b.ListSessions(ctx)
+	if err != nil && !errors.Is(err, ErrNoServer) {
+		return nil, err
+	}
+	agents := GetAgentStates(ctx, b) // Get agent states for all sessions at once
 
-		layoutNames[name] = true
-		layoutPath := filepath.Join(layoutsDir, e.Name())
-		layouts = append(layouts, Session{
-			Name:        name,
-			Description: getSessionDescription(layoutPath),
-			IsRunning:   false,
-			IsLayout:    true,
-			ProjectRoot: getSessionRoot(layoutPath, home),
-			ProjectType: detectProjectType(name),
-			Icon:        "•",
-		})
+	for _, layout := range layoutsByName {
+		layouts = append(layouts, layout)
 	}
 
 	// Then, collect all running tmux sessions
-	for _, runningName := range runningNames {
+	for _, info := range running {
 		session := Session{
-			Name:         runningName,
-			IsRunning:    true,
-			IsLayout:     false,
-			Icon:         "•",
-			ClaudeStatus: claudeStatus[runningName],
+			Name:      info.Name,
+			IsRunning: true,
+			IsLayout:  false,
+			Icon:      "•",
+			Agents:    agents[info.Name],
+			Created:   info.Created,
+			Activity:  info.Activity,
+			Attached:  info.Attached,
+			Windows:   info.Windows,
+			Alerts:    info.Alerts,
 		}
-		if layoutNames[runningName] {
+		if layout, ok := layoutsByName[info.Name]; ok {
 			// Has a layout - get description from it
-			layoutPath := filepath.Join(layoutsDir, runningName+".session.sh")
-			session.Description = getSessionDescription(layoutPath)
-			session.ProjectRoot = getSessionRoot(layoutPath, home)
-			session.ProjectType = detectProjectType(runningName)
+			session.Description = layout.Description
+			session.ProjectRoot = layout.ProjectRoot
+			session.ProjectType = layout.ProjectType
+			session.Source = layout.Source
+			session.LayoutPath = layout.LayoutPath
+			session.Layout = layout.Layout
+			session.LayoutIssues = layout.LayoutIssues
 		} else {
 			// Orphan session
 			session.Description = "(no layout)"
@@ -100,60 +100,152 @@ func GetAllSessions() ([]Session, error) {
 	return append(runningSessions, layouts...), nil
 }
 
-// getRunningTmuxSessions returns list of currently running tmux sessions
-func getRunningTmuxSessions() []string {
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}")
-	output, err := cmd.Output()
+// GetLayouts returns all tmuxifier layouts and native specs, sorted by name
+func GetLayouts() ([]Session, error) {
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return []string{}
+		return nil, err
 	}
 
-	var sessions []string
-	for _, line := range strings.Split(string(output), "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			sessions = append(sessions, line)
-		}
+	layoutsByName, err := getLayouts(home)
+	if err != nil {
+		return nil, err
+	}
+
+	var layouts []Session
+	for _, layout := range layoutsByName {
+		layouts = append(layouts, layout)
 	}
-	return sessions
+	sort.Slice(layouts, func(i, j int) bool {
+		return layouts[i].Name < layouts[j].Name
+	})
+	return layouts, nil
 }
 
-// getSessionRoot extracts the default session_root path from a layout file
-// Parses patterns like: session_root "${SESSION_ROOT:-~/path}" or session_root "~/path"
-func getSessionRoot(layoutPath string, home string) string {
-	content, err := os.ReadFile(layoutPath)
-	if err != nil {
-		return ""
+// getLayouts collects tmuxifier layouts and native specs by name. A native
+// spec wins over a tmuxifier layout of the same name. Either directory may
+// be missing.
+func getLayouts(home string) (map[string]Session, error) {
+	layouts := make(map[string]Session)
+
+	// First, collect all tmuxifier layouts
+	layoutsDir := filepath.Join(home, ".tmuxifier", "layouts")
+	entries, err := os.ReadDir(layoutsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
 	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".session.sh") || e.Name() == "new-session.session.sh" {
+			continue
+		}
 
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "session_root ") {
-			// Extract the argument
-			arg := strings.TrimPrefix(line, "session_root ")
-			arg = strings.Trim(arg, "\"'")
-
-			// Handle ${SESSION_ROOT:-default} pattern
-			if strings.HasPrefix(arg, "${SESSION_ROOT:-") && strings.HasSuffix(arg, "}") {
-				arg = strings.TrimPrefix(arg, "${SESSION_ROOT:-")
-				arg = strings.TrimSuffix(arg, "}")
-			}
+		name := strings.TrimSuffix(e.Name(), ".session.sh")
+		if name == "" {
+			continue
+		}
 
-			// Skip if it's a variable reference we can't resolve
-			if strings.HasPrefix(arg, "$") {
-				return ""
+		layoutPath := filepath.Join(layoutsDir, e.Name())
+		layout := Session{
+			Name:        name,
+			Description: getSessionDescription(layoutPath),
+			IsRunning:   false,
+			IsLayout:    true,
+			ProjectType: detectProjectType(name),
+			Icon:        "•",
+			Source:      SourceTmuxifier,
+			LayoutPath:  layoutPath,
+		}
+		if parsed, err := ParseTmuxifierLayout(layoutPath); err != nil {
+			layout.LayoutIssues = []string{err.Error()}
+		} else {
+			layout.Layout = &parsed.Spec
+			layout.LayoutIssues = parsed.Warnings
+			layout.ProjectRoot = expandPath(parsed.Spec.Root)
+			if err := parsed.Spec.Validate(); err != nil {
+				layout.LayoutIssues = append(layout.LayoutIssues, err.Error())
 			}
+		}
+		layouts[name] = layout
+	}
 
-			// Expand ~ to home directory
-			if strings.HasPrefix(arg, "~") {
-				arg = filepath.Join(home, strings.TrimPrefix(arg, "~"))
-			}
+	// Then, native specs
+	specsDir := filepath.Join(home, ".config", "warpp", "sessions")
+	entries, err = os.ReadDir(specsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := specName(e.Name())
+		if e.IsDir() || name == "" || name == "new-session" {
+			continue
+		}
+
+		specPath := filepath.Join(specsDir, e.Name())
+		layout := Session{
+			Name:        name,
+			IsLayout:    true,
+			ProjectType: detectProjectType(name),
+			Icon:        "•",
+			Source:      SourceWarpp,
+			LayoutPath:  specPath,
+		}
+		if spec, err := LoadSpec(specPath); err != nil {
+			// Keep broken specs visible so the error shows up on launch
+			layout.Description = "Invalid spec: " + err.Error()
+			layout.LayoutIssues = []string{err.Error()}
+		} else {
+			layout.Name = spec.Name
+			layout.Description = spec.Description
+			layout.ProjectRoot = expandPath(spec.Root)
+			layout.Layout = spec
+		}
+		if layout.Description == "" {
+			layout.Description = "Tmux session layout"
+		}
+		layouts[layout.Name] = layout
+	}
+
+	return layouts, nil
+}
+
+// StartLayout creates a session from a layout without attaching to it.
+// sessionName and root override the layout's own when non-empty.
+func StartLayout(ctx context.Context, layout Session, sessionName, root string) error {
+	switch layout.Source {
+	case SourceWarpp:
+		spec, err := LoadSpec(layout.LayoutPath)
+		if err != nil {
+			return err
+		}
+		return spec.Start(ctx, sessionName, root)
+	case SourceTmuxifier:
+		// tmuxifier layouts read these to override session_root and the session name
+		var env []string
+		if root != "" {
+			env = append(env, "SESSION_ROOT="+root)
+		}
+		if sessionName == "" {
+			sessionName = layout.Name
+		} else {
+			env = append(env, "SESSION_NAME="+sessionName)
+		}
+		return LoadTmuxifierSession(ctx, layout.Name, sessionName, env...)
+	}
+	return fmt.Errorf("%s has no layout to start", layout.Name)
+}
 
-			return arg
+// LoadTmuxifierSession runs tmuxifier load-session for a layout with env
+// added to the environment. tmuxifier ends by attaching to the session,
+// which fails without a terminal, so loading succeeded when the session it
+// creates, sessionName, now exists.
+func LoadTmuxifierSession(ctx context.Context, layoutName, sessionName string, env ...string) error {
+	_, err := RunEnv(ctx, env, "tmuxifier", "load-session", layoutName)
+	if err != nil {
+		if _, hasErr := Run(ctx, "tmux", "has-session", "-t", "="+sessionName); hasErr == nil {
+			return nil
 		}
 	}
-	return ""
+	return err
 }
 
 // getSessionDescription extracts description from session file comments
@@ -194,27 +286,61 @@ func detectProjectType(name string) string {
 	}
 }
 
-// getProjectIcon returns simple indicator for project type
-func getProjectIcon(name string) string {
-	return "•" // Simple bullet point
+// SelectTarget makes a window, and a pane within it when pane isn't -1, the
+// current one of a session, so attaching lands on it
+func SelectTarget(ctx context.Context, sessionName string, window, pane int) error {
+	target := fmt.Sprintf("=%s:%d", sessionName, window)
+	if _, err := Run(ctx, "tmux", "select-window", "-t", target); err != nil {
+		return err
+	}
+	if pane >= 0 {
+		if _, err := Run(ctx, "tmux", "select-pane", "-t", fmt.Sprintf("%s.%d", target, pane)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// LaunchSession launches a tmuxifier session
-func LaunchSession(name string) error {
-	cmd := exec.Command("tmuxifier", "load-session", name)
-	if err := cmd.Run(); err != nil {
+// notifyClientFormat is the list-clients format read by NotifyClients
+const notifyClientFormat = "#{client_name}\t#{client_tty}\t#{client_control_mode}"
+
+// NotifyClients shows message on every attached terminal client, when it
+// isn't empty, and rings their bell if bell is set. Control-mode clients
+// such as warpp's own are skipped.
+func NotifyClients(ctx context.Context, message string, bell bool) error {
+	output, err := Run(ctx, "tmux", "list-clients", "-F", notifyClientFormat)
+	if err != nil {
 		return err
 	}
-
-	// Attach or switch to session
-	var attachCmd *exec.Cmd
-	if os.Getenv("TMUX") != "" {
-		attachCmd = exec.Command("tmux", "switch-client", "-t", name)
-	} else {
-		attachCmd = exec.Command("tmux", "attach-session", "-t", name)
+	var errs []error
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || fields[2] == "1" {
+			continue
+		}
+		if message != "" {
+			if _, err := Run(ctx, "tmux", "display-message", "-c", fields[0], message); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if bell {
+			if err := ringBell(fields[1]); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	return attachCmd.Run()
+// ringBell writes a BEL to a client's terminal
+func ringBell(tty string) error {
+	f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString("\a")
+	return err
 }
 
 func contains(slice []string, item string) bool {
@@ -226,171 +352,90 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// KillSession kills a running tmux session
-func KillSession(name string) error {
-	cmd := exec.Command("tmux", "kill-session", "-t", name)
-	return cmd.Run()
-}
-
 // IsGitRepo checks if a directory is a git repository
-func IsGitRepo(path string) bool {
-	cmd := exec.Command("git", "-C", path, "rev-parse", "--git-dir")
-	return cmd.Run() == nil
+func IsGitRepo(ctx context.Context, path string) bool {
+	_, err := Run(ctx, "git", "-C", path, "rev-parse", "--git-dir")
+	return err == nil
 }
 
 // CreateWorktree creates a new git worktree with a new branch
 // Returns the path to the new worktree
-func CreateWorktree(basePath, newDirName, branchName string) (string, error) {
+func CreateWorktree(ctx context.Context, basePath, newDirName, branchName string) (string, error) {
 	// Worktree goes in sibling directory
 	parentDir := filepath.Dir(basePath)
 	worktreePath := filepath.Join(parentDir, newDirName)
 
 	// Create worktree with new branch
-	cmd := exec.Command("git", "-C", basePath, "worktree", "add", "-b", branchName, worktreePath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to create worktree: %s", string(output))
+	if _, err := Run(ctx, "git", "-C", basePath, "worktree", "add", "-b", branchName, worktreePath); err != nil {
+		return "", fmt.Errorf("failed to create worktree: %w", err)
 	}
 
 	return worktreePath, nil
 }
 
-// GetRunningSessionNames returns a list of running tmux session names (exported for use in main.go)
-func GetRunningSessionNames() []string {
-	return getRunningTmuxSessions()
-}
-
-// CapturePane captures the content of a tmux session's active pane
-func CapturePane(sessionName string, height int) string {
-	// Capture the last N lines from the active pane with ANSI escape sequences (-e)
-	cmd := exec.Command("tmux", "capture-pane", "-t", sessionName, "-p", "-e", "-S", fmt.Sprintf("-%d", height))
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return string(output)
-}
-
 // PaneInfo holds information about a single pane
 type PaneInfo struct {
-	Index   int
-	Width   int
-	Height  int
-	Content string
-}
-
-// GetSessionPanes returns all panes in the active window of a session
-func GetSessionPanes(sessionName string) []PaneInfo {
-	// List all panes in the session's current window
-	cmd := exec.Command("tmux", "list-panes", "-t", sessionName, "-F", "#{pane_index}")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil
-	}
-
-	var panes []PaneInfo
-	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-		if line == "" {
-			continue
-		}
-		var idx int
-		fmt.Sscanf(line, "%d", &idx)
-		panes = append(panes, PaneInfo{Index: idx})
-	}
-
-	return panes
-}
-
-// CapturePaneByIndex captures content from a specific pane
-func CapturePaneByIndex(sessionName string, paneIndex int, height int) string {
-	target := fmt.Sprintf("%s:.%d", sessionName, paneIndex)
-	// Use -e to preserve ANSI escape sequences (colors)
-	cmd := exec.Command("tmux", "capture-pane", "-t", target, "-p", "-e", "-S", fmt.Sprintf("-%d", height))
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return string(output)
+	Session   string
+	ID        string // tmux pane id, e.g. %3
+	Index     int
+	TTY       string
+	PID       int // pid of the pane's first process, usually its shell
+	Left      int // position and size within the window, in cells
+	Top       int
+	Width     int
+	Height    int
+	Active    bool   // the window's active pane
+	Command   string // pane_current_command, e.g. nvim
+	Path      string // pane_current_path
+	Content   string
+	HookState string // agent state last reported by warpp hook, e.g. claude:waiting
 }
 
-// capturePanePlain captures content from a specific pane without ANSI codes (for text matching)
-func capturePanePlain(sessionName string, paneIndex int, height int) string {
-	target := fmt.Sprintf("%s:.%d", sessionName, paneIndex)
-	cmd := exec.Command("tmux", "capture-pane", "-t", target, "-p", "-S", fmt.Sprintf("-%d", height))
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return string(output)
+// WindowInfo holds a window of a session and its panes
+type WindowInfo struct {
+	Session string
+	Index   int
+	Name    string
+	Active  bool   // the session's current window
+	Layout  string // window_layout, as select-layout takes it
+	Panes   []PaneInfo
 }
 
-// GetClaudeSessionStatus returns a map of session name -> claude status
-// Uses process TTY matching for reliable detection
-func GetClaudeSessionStatus() map[string]string {
-	result := make(map[string]string)
-
-	// Step 1: Get all claude CLI processes and their TTYs
-	cmd := exec.Command("ps", "aux")
-	output, err := cmd.Output()
-	if err != nil {
-		return result
-	}
-
-	// Find claude processes and extract TTYs
-	claudeTTYs := make(map[string]bool)
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		// Match "claude" but not "Claude.app"
-		if strings.Contains(line, "claude") && !strings.Contains(line, "Claude.app") && !strings.Contains(line, "grep") {
-			fields := strings.Fields(line)
-			if len(fields) >= 7 {
-				tty := fields[6] // TTY column
-				claudeTTYs[tty] = true
-			}
+// processPanes returns the pane each process runs in, by pid. A process
+// belongs to a pane when it descends from the pane's first process or,
+// failing that, when its controlling terminal is the pane's TTY.
+func processPanes(procs []Process, panes []PaneInfo) map[int]PaneInfo {
+	byPID := make(map[int]PaneInfo)
+	byTTY := make(map[string]PaneInfo)
+	for _, pane := range panes {
+		if pane.PID > 0 {
+			byPID[pane.PID] = pane
+		}
+		if pane.TTY != "" {
+			byTTY[pane.TTY] = pane
 		}
 	}
-
-	// Step 2: Map TTYs to tmux sessions
-	cmd = exec.Command("tmux", "list-panes", "-a", "-F", "#{session_name} #{pane_tty} #{pane_index}")
-	output, err = cmd.Output()
-	if err != nil {
-		return result
+	parents := make(map[int]int, len(procs))
+	for _, proc := range procs {
+		parents[proc.PID] = proc.PPID
 	}
 
-	sessionPanes := make(map[string][]int) // session -> list of pane indices with claude
-	lines = strings.Split(string(output), "\n")
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) >= 3 {
-			sessionName := fields[0]
-			paneTTY := fields[1] // e.g., /dev/ttys044
-			paneIdx, _ := strconv.Atoi(fields[2])
-
-			// Convert TTY format: /dev/ttys044 -> s044
-			shortTTY := strings.TrimPrefix(paneTTY, "/dev/tty")
-
-			if claudeTTYs[shortTTY] {
-				sessionPanes[sessionName] = append(sessionPanes[sessionName], paneIdx)
+	result := make(map[int]PaneInfo)
+	for _, proc := range procs {
+		// Walk up the ancestry; the depth limit guards against pid reuse loops
+		found := false
+		for pid, depth := proc.PID, 0; pid > 1 && depth < 64; pid, depth = parents[pid], depth+1 {
+			if pane, ok := byPID[pid]; ok {
+				result[proc.PID] = pane
+				found = true
+				break
 			}
 		}
-	}
-
-	// Step 3: For each session with claude, check if executing or idle
-	for sessionName, paneIndices := range sessionPanes {
-		status := "idle" // default to idle
-
-		for _, paneIdx := range paneIndices {
-			content := capturePanePlain(sessionName, paneIdx, 15)
-			// Check for active status line: "· <status>… (esc to interrupt"
-			// This only appears when Claude is actively working, not in scrollback
-			if strings.Contains(content, "(esc to interrupt") {
-				status = "executing"
-				break
+		if !found && proc.TTY != "" {
+			if pane, ok := byTTY[proc.TTY]; ok {
+				result[proc.PID] = pane
 			}
 		}
-
-		result[sessionName] = status
 	}
-
 	return result
 }
@@ -0,0 +1,159 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withHome points the home directory at a temporary one holding the given
+// files, relative to it
+func withHome(t *testing.T, files map[string]string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testDetector detects claude by its process, waiting on a choice menu
+func testDetector(t *testing.T) {
+	t.Helper()
+	d, err := NewAgentDetector("claude", `(^|/)claude(\s|$)`, []string{`esc to interrupt`}, nil, []string{`Do you want to proceed`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAgentDetectors([]AgentDetector{d})
+	t.Cleanup(func() { SetAgentDetectors(nil) })
+}
+
+func TestGetAllSessions(t *testing.T) {
+	layouts := map[string]string{
+		".config/warpp/sessions/api.yaml":  "description: API server\nroot: /srv/api\n",
+		".config/warpp/sessions/docs.yaml": "description: Docs site\n",
+	}
+
+	type row struct {
+		Name        string
+		IsRunning   bool
+		IsLayout    bool
+		Description string
+		Agents      []AgentState
+	}
+	tests := []struct {
+		name   string
+		script func(b *FakeBackend)
+		want   []row
+	}{
+		{
+			name:   "no server lists only layouts",
+			script: func(b *FakeBackend) {},
+			want: []row{
+				{Name: "api", IsLayout: true, Description: "API server"},
+				{Name: "docs", IsLayout: true, Description: "Docs site"},
+			},
+		},
+		{
+			name: "running sessions first, by name, with their layouts",
+			script: func(b *FakeBackend) {
+				b.AddSession("zsh")
+				b.AddSession("api")
+			},
+			want: []row{
+				{Name: "api", IsRunning: true, Description: "API server"},
+				{Name: "zsh", IsRunning: true, Description: "(no layout)"},
+				{Name: "api", IsLayout: true, Description: "API server"},
+				{Name: "docs", IsLayout: true, Description: "Docs site"},
+			},
+		},
+		{
+			name: "agents in panes",
+			script: func(b *FakeBackend) {
+				b.AddSession("api", PaneInfo{Index: 0}, PaneInfo{Index: 1})
+				b.AddProcess("api", 1, "/usr/local/bin/claude --continue")
+				b.SetPaneContent("api", 1, "Do you want to proceed?\n❯ 1. Yes")
+				b.AddSession("web")
+				b.AddProcess("web", 0, "claude")
+				b.SetPaneContent("web", 0, "Reading files (esc to interrupt)")
+			},
+			want: []row{
+				{Name: "api", IsRunning: true, Description: "API server", Agents: []AgentState{{Agent: "claude", State: AgentWaiting, Pane: 1, PaneID: "%1"}}},
+				{Name: "web", IsRunning: true, Description: "(no layout)", Agents: []AgentState{{Agent: "claude", State: AgentExecuting, Pane: 0, PaneID: "%2"}}},
+				{Name: "api", IsLayout: true, Description: "API server"},
+				{Name: "docs", IsLayout: true, Description: "Docs site"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withHome(t, layouts)
+			testDetector(t)
+			b := NewFakeBackend()
+			tt.script(b)
+
+			sessions, err := GetAllSessions(context.Background(), b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []row
+			for _, s := range sessions {
+				got = append(got, row{s.Name, s.IsRunning, s.IsLayout, s.Description, s.Agents})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeBackendCapturePane(t *testing.T) {
+	b := NewFakeBackend()
+	b.AddSession("web", PaneInfo{Index: 0}, PaneInfo{Index: 1, Active: true})
+	b.SetPaneContent("web", 0, "one\ntwo\n\x1b[31mthree\x1b[0m")
+	b.SetPaneContent("web", 1, "server running")
+
+	tests := []struct {
+		name    string
+		session string
+		pane    int
+		height  int
+		ansi    bool
+		want    string
+		wantErr error
+	}{
+		{name: "whole pane", session: "web", pane: 0, ansi: true, want: "one\ntwo\n\x1b[31mthree\x1b[0m"},
+		{name: "last lines", session: "web", pane: 0, height: 2, ansi: true, want: "two\n\x1b[31mthree\x1b[0m"},
+		{name: "plain text", session: "web", pane: 0, height: 1, want: "three"},
+		{name: "active pane", session: "web", pane: ActivePane, want: "server running"},
+		{name: "missing pane", session: "web", pane: 5, wantErr: ErrSessionNotFound},
+		{name: "missing session", session: "api", pane: 0, wantErr: ErrSessionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.CapturePane(context.Background(), tt.session, tt.pane, tt.height, tt.ansi)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package tmux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layout sources recorded on Session.Source
+const (
+	SourceTmuxifier = "tmuxifier" // ~/.tmuxifier/layouts/<name>.session.sh
+	SourceWarpp     = "warpp"     // ~/.config/warpp/sessions/<name>.yaml
+)
+
+// Spec is a warpp-native session layout, built directly with tmux commands
+//
+//	name: webapp
+//	description: Frontend and API
+//	root: ~/code/webapp
+//	env:
+//	  NODE_ENV: development
+//	windows:
+//	  - name: editor
+//	    panes:
+//	      - command: nvim
+//	      - split: horizontal
+//	        size: 40%
+//	        root: server
+//	        command: npm run dev
+//	select_window: editor
+type Spec struct {
+	Name         string            `yaml:"name,omitempty"`
+	Description  string            `yaml:"description,omitempty"`
+	Root         string            `yaml:"root,omitempty"`
+	Env          map[string]string `yaml:"env,omitempty"`
+	Windows      []SpecWindow      `yaml:"windows,omitempty"`
+	SelectWindow string            `yaml:"select_window,omitempty"` // window name, or position counting from 0, to select; defaults to the first
+}
+
+// SpecWindow is a window of a Spec
+type SpecWindow struct {
+	Name   string     `yaml:"name,omitempty"`
+	Root   string     `yaml:"root,omitempty"`   // relative to the session root
+	Layout string     `yaml:"layout,omitempty"` // tmux layout applied after splitting, e.g. tiled
+	Panes  []SpecPane `yaml:"panes,omitempty"`  // the first pane is the window itself
+}
+
+// SpecPane is a pane of a SpecWindow. Every pane after the first is created
+// by splitting an earlier one.
+type SpecPane struct {
+	Split    string   `yaml:"split,omitempty"`    // "horizontal" (side by side) or "vertical" (stacked)
+	Size     string   `yaml:"size,omitempty"`     // new pane size: "30%" or a line/column count
+	Target   *int     `yaml:"target,omitempty"`   // index of the pane to split; defaults to the previous one
+	Root     string   `yaml:"root,omitempty"`     // relative to the window root
+	Command  string   `yaml:"command,omitempty"`  // typed into the pane once its shell starts
+	Commands []string `yaml:"commands,omitempty"` // more commands, typed after Command
+}
+
+// SpecsDir returns the directory native session specs are loaded from
+func SpecsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "warpp", "sessions"), nil
+}
+
+// LoadSpec reads and validates a spec file. Name defaults to the file name.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if spec.Name == "" {
+		spec.Name = specName(filepath.Base(path))
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &spec, nil
+}
+
+// FindSpec loads the native spec with the given name. The error wraps
+// os.ErrNotExist when there is none.
+func FindSpec(name string) (*Spec, error) {
+	dir, err := SpecsDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return LoadSpec(path)
+		}
+	}
+	return nil, fmt.Errorf("no session spec named %q in %s: %w", name, dir, os.ErrNotExist)
+}
+
+// Validate checks split directions, sizes, pane targets and the selected
+// window
+func (s *Spec) Validate() error {
+	if _, err := s.selectedWindow(); err != nil {
+		return fmt.Errorf("select_window: %w", err)
+	}
+	for wi, win := range s.Windows {
+		for pi, pane := range win.Panes {
+			where := fmt.Sprintf("window %d pane %d", wi, pi)
+			if pi > 0 {
+				if _, err := splitFlag(pane.Split); err != nil {
+					return fmt.Errorf("%s: %w", where, err)
+				}
+			}
+			if pane.Size != "" {
+				size := strings.TrimSuffix(pane.Size, "%")
+				if n, err := strconv.Atoi(size); err != nil || n <= 0 {
+					return fmt.Errorf("%s: invalid size %q", where, pane.Size)
+				}
+			}
+			if pane.Target != nil && (*pane.Target < 0 || *pane.Target >= pi) {
+				return fmt.Errorf("%s: target %d must be an earlier pane", where, *pane.Target)
+			}
+		}
+	}
+	return nil
+}
+
+// selectedWindow returns the position of the window to select. A name
+// wins over a number, which counts windows from 0 whatever the tmux
+// base-index.
+func (s *Spec) selectedWindow() (int, error) {
+	if s.SelectWindow == "" {
+		return 0, nil
+	}
+	for i, win := range s.Windows {
+		if win.Name == s.SelectWindow {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(s.SelectWindow)
+	if err != nil {
+		return 0, fmt.Errorf("no window named %q", s.SelectWindow)
+	}
+	if count := max(len(s.Windows), 1); n < 0 || n >= count {
+		return 0, fmt.Errorf("window %d: no such window, windows are 0-%d", n, count-1)
+	}
+	return n, nil
+}
+
+// PaneCount returns the number of panes across all windows
+func (s *Spec) PaneCount() int {
+	if len(s.Windows) == 0 {
+		return 1
+	}
+	count := 0
+	for _, win := range s.Windows {
+		count += max(len(win.Panes), 1)
+	}
+	return count
+}
+
+// WindowCount returns the number of windows, counting the default window
+// of a spec that declares none
+func (s *Spec) WindowCount() int {
+	return max(len(s.Windows), 1)
+}
+
+// YAML encodes the spec in the native session spec format
+func (s *Spec) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Start creates the session with tmux new-session, new-window, split-window
+// and send-keys. Empty sessionName or root fall back to the spec's own. If
+// any step fails the partly built session is killed.
+func (s *Spec) Start(ctx context.Context, sessionName, root string) error {
+	if sessionName == "" {
+		sessionName = s.Name
+	}
+	if root == "" {
+		root = s.Root
+	}
+	root = expandPath(root)
+	selected, err := s.selectedWindow()
+	if err != nil {
+		return fmt.Errorf("select_window: %w", err)
+	}
+
+	windows := s.Windows
+	if len(windows) == 0 {
+		windows = []SpecWindow{{}}
+	}
+
+	var envArgs []string
+	for _, key := range sortedKeys(s.Env) {
+		envArgs = append(envArgs, "-e", key+"="+s.Env[key])
+	}
+
+	var selectedWindow, selectedPane string
+	for wi, win := range windows {
+		winRoot := joinRoot(root, win.Root)
+		panes := win.Panes
+		if len(panes) == 0 {
+			panes = []SpecPane{{}}
+		}
+
+		// Create the window (or the session, for the first one) with its first pane
+		var args []string
+		if wi == 0 {
+			args = []string{"new-session", "-d", "-s", sessionName}
+		} else {
+			args = []string{"new-window", "-d", "-t", "=" + sessionName + ":"}
+		}
+		args = append(args, "-P", "-F", "#{window_id} #{pane_id}")
+		args = appendStartDir(args, joinRoot(winRoot, panes[0].Root))
+		if win.Name != "" {
+			args = append(args, "-n", os.Expand(win.Name, expandVar))
+		}
+		args = append(args, envArgs...)
+
+		output, err := Run(ctx, "tmux", args...)
+		if err != nil {
+			return s.abort(sessionName, wi > 0, err)
+		}
+		ids := strings.Fields(string(output))
+		if len(ids) < 2 {
+			return s.abort(sessionName, wi > 0, fmt.Errorf("unexpected tmux output: %q", output))
+		}
+		windowID, paneIDs := ids[0], []string{ids[1]}
+		if wi == selected {
+			selectedWindow, selectedPane = windowID, ids[1]
+		}
+
+		// Split the remaining panes off earlier ones
+		for pi, pane := range panes[1:] {
+			target := paneIDs[pi]
+			if pane.Target != nil {
+				target = paneIDs[*pane.Target]
+			}
+			flag, _ := splitFlag(pane.Split)
+			args := []string{"split-window", flag, "-t", target, "-P", "-F", "#{pane_id}"}
+			args = appendStartDir(args, joinRoot(winRoot, pane.Root))
+			if pane.Size != "" {
+				args = append(args, "-l", pane.Size)
+			}
+			args = append(args, envArgs...)
+
+			output, err := Run(ctx, "tmux", args...)
+			if err != nil {
+				return s.abort(sessionName, true, err)
+			}
+			paneIDs = append(paneIDs, strings.TrimSpace(string(output)))
+		}
+
+		if win.Layout != "" {
+			if _, err := Run(ctx, "tmux", "select-layout", "-t", windowID, win.Layout); err != nil {
+				return s.abort(sessionName, true, err)
+			}
+		}
+
+		// Type startup commands once every pane exists
+		for pi, pane := range panes {
+			for _, command := range pane.StartupCommands() {
+				if _, err := Run(ctx, "tmux", "send-keys", "-t", paneIDs[pi], command, "Enter"); err != nil {
+					return s.abort(sessionName, true, err)
+				}
+			}
+		}
+	}
+
+	// Start on the selected window's first pane
+	if _, err := Run(ctx, "tmux", "select-window", "-t", selectedWindow); err != nil {
+		return s.abort(sessionName, true, err)
+	}
+	if _, err := Run(ctx, "tmux", "select-pane", "-t", selectedPane); err != nil {
+		return s.abort(sessionName, true, err)
+	}
+	return nil
+}
+
+// abort kills a partly built session and returns err
+func (s *Spec) abort(sessionName string, created bool, err error) error {
+	if created {
+		Run(context.Background(), "tmux", "kill-session", "-t", "="+sessionName)
+	}
+	return err
+}
+
+// StartupCommands returns Command followed by Commands
+func (p SpecPane) StartupCommands() []string {
+	var commands []string
+	if p.Command != "" {
+		commands = append(commands, p.Command)
+	}
+	return append(commands, p.Commands...)
+}
+
+// splitFlag maps a split direction to the split-window flag
+func splitFlag(split string) (string, error) {
+	switch strings.ToLower(split) {
+	case "", "vertical", "v":
+		return "-v", nil
+	case "horizontal", "h":
+		return "-h", nil
+	default:
+		return "", fmt.Errorf("invalid split %q (want horizontal or vertical)", split)
+	}
+}
+
+// specName returns the layout name for a spec file name, or "" if it isn't one
+func specName(fileName string) string {
+	for _, ext := range []string{".yaml", ".yml"} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext)
+		}
+	}
+	return ""
+}
+
+// expandPath expands ~ and environment variables in a path
+func expandPath(path string) string {
+	path = os.Expand(path, expandVar)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// expandVar returns an environment variable for os.Expand, honouring the
+// shell's ${VAR:-default} and ${VAR-default}
+func expandVar(name string) string {
+	if i := strings.Index(name, ":-"); i >= 0 {
+		if value := os.Getenv(name[:i]); value != "" {
+			return value
+		}
+		return os.Expand(name[i+2:], expandVar)
+	}
+	if i := strings.Index(name, "-"); i >= 0 {
+		if value, ok := os.LookupEnv(name[:i]); ok {
+			return value
+		}
+		return os.Expand(name[i+1:], expandVar)
+	}
+	return os.Getenv(name)
+}
+
+// joinRoot resolves dir against base unless it's absolute or empty
+func joinRoot(base, dir string) string {
+	dir = expandPath(dir)
+	if dir == "" {
+		return base
+	}
+	if filepath.IsAbs(dir) || base == "" {
+		return dir
+	}
+	return filepath.Join(base, dir)
+}
+
+// appendStartDir adds -c dir unless dir is empty, in which case tmux uses
+// its default start directory
+func appendStartDir(args []string, dir string) []string {
+	if dir == "" {
+		return args
+	}
+	return append(args, "-c", dir)
+}
+
+// sortedKeys returns map keys in order, for deterministic command lines
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package tmux
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpecValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr string
+	}{
+		{name: "empty", spec: Spec{}},
+		{
+			name: "splits, sizes and targets",
+			spec: Spec{Windows: []SpecWindow{{Panes: []SpecPane{
+				{Command: "nvim"},
+				{Split: "horizontal", Size: "40%"},
+				{Split: "v", Size: "12", Target: intPtr(0)},
+			}}}},
+		},
+		{
+			name:    "unknown split",
+			spec:    Spec{Windows: []SpecWindow{{Panes: []SpecPane{{}, {Split: "diagonal"}}}}},
+			wantErr: `window 0 pane 1: invalid split "diagonal"`,
+		},
+		{
+			name: "split of the first pane is ignored",
+			spec: Spec{Windows: []SpecWindow{{Panes: []SpecPane{{Split: "diagonal"}}}}},
+		},
+		{
+			name:    "size not a number",
+			spec:    Spec{Windows: []SpecWindow{{Panes: []SpecPane{{}, {Size: "half"}}}}},
+			wantErr: `window 0 pane 1: invalid size "half"`,
+		},
+		{
+			name:    "zero size",
+			spec:    Spec{Windows: []SpecWindow{{}, {Panes: []SpecPane{{Size: "0%"}}}}},
+			wantErr: `window 1 pane 0: invalid size "0%"`,
+		},
+		{
+			name:    "target a later pane",
+			spec:    Spec{Windows: []SpecWindow{{Panes: []SpecPane{{}, {Target: intPtr(1)}}}}},
+			wantErr: "window 0 pane 1: target 1 must be an earlier pane",
+		},
+		{
+			name:    "negative target",
+			spec:    Spec{Windows: []SpecWindow{{Panes: []SpecPane{{}, {Target: intPtr(-1)}}}}},
+			wantErr: "window 0 pane 1: target -1 must be an earlier pane",
+		},
+		{
+			name: "select window by name",
+			spec: Spec{Windows: []SpecWindow{{Name: "editor"}, {Name: "server"}}, SelectWindow: "server"},
+		},
+		{
+			name: "select window by position",
+			spec: Spec{Windows: []SpecWindow{{Name: "editor"}, {Name: "server"}}, SelectWindow: "1"},
+		},
+		{
+			name: "select the default window",
+			spec: Spec{SelectWindow: "0"},
+		},
+		{
+			name: "window names win over positions",
+			spec: Spec{Windows: []SpecWindow{{Name: "editor"}, {Name: "7"}}, SelectWindow: "7"},
+		},
+		{
+			name:    "select a window past the last",
+			spec:    Spec{Windows: []SpecWindow{{Name: "editor"}, {Name: "server"}}, SelectWindow: "2"},
+			wantErr: "select_window: window 2: no such window, windows are 0-1",
+		},
+		{
+			name:    "select a negative window",
+			spec:    Spec{Windows: []SpecWindow{{Name: "editor"}}, SelectWindow: "-1"},
+			wantErr: "select_window: window -1: no such window, windows are 0-0",
+		},
+		{
+			name:    "select a missing window",
+			spec:    Spec{Windows: []SpecWindow{{Name: "editor"}}, SelectWindow: "logs"},
+			wantErr: `select_window: no window named "logs"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSpec(t *testing.T) {
+	withHome(t, map[string]string{
+		"webapp.yaml": "root: ~/code/webapp\nwindows:\n  - name: editor\n    panes:\n      - command: nvim\n      - split: horizontal\n        size: 40%\n",
+		"named.yml":   "name: other\n",
+		"bad.yaml":    "windows:\n  - panes:\n      - {}\n      - split: sideways\n",
+		"broken.yaml": "windows: [\n",
+	})
+	home := os.Getenv("HOME")
+
+	spec, err := LoadSpec(home + "/webapp.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "webapp" || spec.Root != "~/code/webapp" || spec.PaneCount() != 2 || spec.WindowCount() != 1 {
+		t.Errorf("got %+v", spec)
+	}
+	if spec, err := LoadSpec(home + "/named.yml"); err != nil || spec.Name != "other" {
+		t.Errorf("named.yml: %+v, %v", spec, err)
+	}
+	for _, name := range []string{"bad.yaml", "broken.yaml"} {
+		if _, err := LoadSpec(home + "/" + name); err == nil || !strings.HasPrefix(err.Error(), name+": ") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	t.Setenv("APP", "shop")
+	t.Setenv("EMPTY", "")
+	tests := []struct {
+		path, want string
+	}{
+		{path: "~/code", want: "/home/test/code"},
+		{path: "$HOME/code/$APP", want: "/home/test/code/shop"},
+		{path: "${APP:-web}", want: "shop"},
+		{path: "${WARPP_TEST_UNSET:-web}", want: "web"},
+		{path: "${EMPTY:-web}", want: "web"},
+		{path: "${EMPTY-web}", want: ""},
+		{path: "${WARPP_TEST_UNSET-$HOME}/code", want: "/home/test/code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := expandPath(tt.path); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
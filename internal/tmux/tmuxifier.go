@@ -0,0 +1,488 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxLoadWindowDepth stops load_window cycles
+const maxLoadWindowDepth = 8
+
+// TmuxifierLayout is a tmuxifier .session.sh file parsed into warpp's layout model
+type TmuxifierLayout struct {
+	Spec     Spec     // windows, panes, roots, commands and the selected window
+	Warnings []string // lines that couldn't be modeled, with line numbers
+}
+
+// tmuxifierParser interprets the tmuxifier layout DSL without running it
+type tmuxifierParser struct {
+	layoutsDir string
+	base       int               // base-index, which window arguments count from
+	paneBase   int               // pane-base-index, which pane arguments count from
+	vars       map[string]string // variables assigned in the file
+	layout     *TmuxifierLayout
+	windowRoot string
+	window     int    // index of the window pane commands apply to, -1 before any
+	pane       int    // current pane index within window
+	conditions []bool // open if blocks, true when their branch depends on a condition
+}
+
+var assignmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+var (
+	baseIndexOnce sync.Once
+	baseIndex     int
+	paneBaseIndex int
+)
+
+// BaseIndexes returns the tmux server's base-index and pane-base-index, the
+// numbers of a session's first window and a window's first pane, or 0 when
+// no server runs
+func BaseIndexes() (window, pane int) {
+	baseIndexOnce.Do(func() {
+		baseIndex = globalNumberOption("base-index")
+		paneBaseIndex = globalNumberOption("pane-base-index")
+	})
+	return baseIndex, paneBaseIndex
+}
+
+// globalNumberOption reads a numeric global tmux option, or 0
+func globalNumberOption(name string) int {
+	output, err := Run(context.Background(), "tmux", "show-options", "-gv", name)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return n
+}
+
+// ParseTmuxifierLayout parses a tmuxifier session file. References to
+// variables set in the file are resolved, while environment variables are
+// kept as written for the shell or the spec to expand when the session
+// starts. Window and pane arguments count from the server's base-index and
+// pane-base-index.
+func ParseTmuxifierLayout(path string) (*TmuxifierLayout, error) {
+	base, paneBase := BaseIndexes()
+	return parseTmuxifierLayout(path, base, paneBase)
+}
+
+// parseTmuxifierLayout parses a tmuxifier session file whose window and
+// pane arguments count from base and paneBase
+func parseTmuxifierLayout(path string, base, paneBase int) (*TmuxifierLayout, error) {
+	p := &tmuxifierParser{
+		layoutsDir: filepath.Dir(path),
+		base:       base,
+		paneBase:   paneBase,
+		vars:       make(map[string]string),
+		layout:     &TmuxifierLayout{},
+		window:     -1,
+	}
+	p.layout.Spec.Name = strings.TrimSuffix(filepath.Base(path), ".session.sh")
+
+	if err := p.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	p.layout.Spec.Description = getSessionDescription(path)
+	return p.layout, nil
+}
+
+// parseFile interprets one file; window files loaded with load_window recurse
+func (p *tmuxifierParser) parseFile(path string, depth int) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(path)
+	for _, line := range joinContinuations(string(content)) {
+		for _, words := range p.splitCommands(line.text) {
+			conditional := p.conditional()
+			warning := p.apply(words, depth)
+			if warning == "" && conditional && !isControlFlow(words[0]) {
+				warning = fmt.Sprintf("%s: inside an if block, applied whether or not its condition holds", words[0])
+			}
+			if warning != "" {
+				p.layout.Warnings = append(p.layout.Warnings, fmt.Sprintf("%s:%d: %s", name, line.number, warning))
+			}
+		}
+	}
+	return nil
+}
+
+// numberedLine is a logical line of a layout file and the number of its
+// first physical line
+type numberedLine struct {
+	text   string
+	number int
+}
+
+// joinContinuations splits content into lines, joining those that end in a
+// backslash to the next as sh does
+func joinContinuations(content string) []numberedLine {
+	var lines []numberedLine
+	var current strings.Builder
+	start := 0
+	for i, line := range strings.Split(content, "\n") {
+		if current.Len() == 0 {
+			start = i + 1
+		}
+		trimmed := strings.TrimRight(line, "\r")
+		// An odd number of trailing backslashes escapes the newline
+		backslashes := len(trimmed) - len(strings.TrimRight(trimmed, `\`))
+		if backslashes%2 == 1 {
+			current.WriteString(trimmed[:len(trimmed)-1])
+			continue
+		}
+		current.WriteString(line)
+		lines = append(lines, numberedLine{current.String(), start})
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		lines = append(lines, numberedLine{current.String(), start})
+	}
+	return lines
+}
+
+// isControlFlow reports whether a command word is sh control flow
+func isControlFlow(word string) bool {
+	switch word {
+	case "if", "then", "elif", "else", "fi":
+		return true
+	}
+	return false
+}
+
+// conditional reports whether commands are inside an if block whose branch
+// depends on a condition. The if initialize_session guard every layout
+// starts with doesn't count: its then branch always runs on a new session.
+func (p *tmuxifierParser) conditional() bool {
+	for _, conditional := range p.conditions {
+		if conditional {
+			return true
+		}
+	}
+	return false
+}
+
+// apply interprets a single command and returns a warning if it can't be modeled
+func (p *tmuxifierParser) apply(words []string, depth int) string {
+	if len(words) == 0 {
+		return ""
+	}
+	if assignmentRegex.MatchString(words[0]) || (words[0] == "export" && len(words) > 1) {
+		for _, word := range words {
+			if key, value, ok := strings.Cut(word, "="); ok && assignmentRegex.MatchString(word) {
+				p.vars[key] = value
+			}
+		}
+		return ""
+	}
+
+	cmd, args := words[0], words[1:]
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch cmd {
+	case "if":
+		// if initialize_session "name"; then ... fi guards the layout
+		guard := arg(0) == "initialize_session"
+		p.conditions = append(p.conditions, !guard)
+		if guard {
+			return p.apply(args, depth)
+		}
+	case "elif", "else":
+		if n := len(p.conditions); n > 0 {
+			p.conditions[n-1] = true
+		}
+	case "fi":
+		if n := len(p.conditions); n > 0 {
+			p.conditions = p.conditions[:n-1]
+		}
+	case "then", "finalize_and_go_to_session", "clock", "synchronize_on", "synchronize_off":
+		// Runtime-only helpers don't change the structure
+	case "initialize_session":
+		if arg(0) != "" {
+			p.layout.Spec.Name = arg(0)
+		}
+	case "session_root":
+		p.layout.Spec.Root = arg(0)
+		p.windowRoot = ""
+	case "window_root":
+		p.windowRoot = arg(0)
+		if p.window >= 0 {
+			if win := &p.layout.Spec.Windows[p.window]; len(win.Panes) == 1 && win.Panes[0].Command == "" {
+				win.Root = arg(0)
+			}
+		}
+	case "new_window":
+		p.layout.Spec.Windows = append(p.layout.Spec.Windows, SpecWindow{
+			Name:  arg(0),
+			Root:  p.windowRoot,
+			Panes: []SpecPane{{Command: arg(1)}},
+		})
+		p.window = len(p.layout.Spec.Windows) - 1
+		p.pane = 0
+	case "split_v", "split_h", "split_vl", "split_hl":
+		win := p.currentWindow()
+		pane := SpecPane{Split: "vertical"}
+		if strings.HasPrefix(cmd, "split_h") {
+			pane.Split = "horizontal"
+		}
+		if size := arg(0); size != "" {
+			pane.Size = size
+			if !strings.HasSuffix(cmd, "l") {
+				pane.Size += "%"
+			}
+		}
+		target, warning := p.paneArg(win, arg(1))
+		if target >= 0 {
+			pane.Target = &target
+		}
+		win.Panes = append(win.Panes, pane)
+		p.pane = len(win.Panes) - 1
+		return warning
+	case "run_cmd":
+		win := p.currentWindow()
+		target, warning := p.paneArg(win, arg(1))
+		if target < 0 {
+			target = p.pane
+		}
+		pane := &win.Panes[target]
+		if pane.Command == "" {
+			pane.Command = arg(0)
+		} else {
+			pane.Commands = append(pane.Commands, arg(0))
+		}
+		return warning
+	case "select_pane":
+		target, warning := p.paneArg(p.currentWindow(), arg(0))
+		if target >= 0 {
+			p.pane = target
+		}
+		return warning
+	case "select_window":
+		// Specs select by name or position, tmux by name or index
+		n, err := strconv.Atoi(arg(0))
+		if err != nil {
+			p.layout.Spec.SelectWindow = arg(0)
+			break
+		}
+		count := len(p.layout.Spec.Windows)
+		if n < p.base || n >= p.base+count {
+			return fmt.Sprintf("window %d: no such window, windows are %d-%d with base-index %d",
+				n, p.base, p.base+count-1, p.base)
+		}
+		p.layout.Spec.SelectWindow = strconv.Itoa(n - p.base)
+	case "select_layout":
+		p.currentWindow().Layout = arg(0)
+	case "balance_windows_vertical":
+		p.currentWindow().Layout = "even-vertical"
+	case "balance_windows_horizontal":
+		p.currentWindow().Layout = "even-horizontal"
+	case "load_window":
+		if depth >= maxLoadWindowDepth {
+			return fmt.Sprintf("load_window %s: nested too deeply", arg(0))
+		}
+		path := arg(0)
+		if !strings.Contains(path, "/") {
+			path = filepath.Join(p.layoutsDir, path+".window.sh")
+		}
+		if err := p.parseFile(expandPath(path), depth+1); err != nil {
+			return fmt.Sprintf("load_window %s: %v", arg(0), err)
+		}
+	default:
+		return fmt.Sprintf("%s: not modeled", cmd)
+	}
+	return ""
+}
+
+// paneArg resolves a pane index argument of a tmuxifier command to a pane
+// of win, or -1 when it's empty or out of range, with a warning for the
+// latter. tmux numbers panes from pane-base-index in screen position order;
+// they're treated as creation order here, which matches simple layouts.
+func (p *tmuxifierParser) paneArg(win *SpecWindow, value string) (int, string) {
+	if value == "" {
+		return -1, ""
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return -1, fmt.Sprintf("pane %q: not a pane index", value)
+	}
+	if index := n - p.paneBase; index >= 0 && index < len(win.Panes) {
+		return index, ""
+	}
+	return -1, fmt.Sprintf("pane %d: no such pane, panes are %d-%d with pane-base-index %d",
+		n, p.paneBase, p.paneBase+len(win.Panes)-1, p.paneBase)
+}
+
+// currentWindow returns the window pane commands apply to, creating the
+// session's default window when commands come before any new_window
+func (p *tmuxifierParser) currentWindow() *SpecWindow {
+	if p.window < 0 {
+		p.layout.Spec.Windows = append(p.layout.Spec.Windows, SpecWindow{
+			Root:  p.windowRoot,
+			Panes: []SpecPane{{}},
+		})
+		p.window = len(p.layout.Spec.Windows) - 1
+		p.pane = 0
+	}
+	return &p.layout.Spec.Windows[p.window]
+}
+
+// splitCommands splits a line into commands of words, honouring quotes,
+// backslashes, comments and unquoted ';', and expanding variables outside
+// single quotes
+func (p *tmuxifierParser) splitCommands(line string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord, inSingle, inDouble := false, false, false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inSingle:
+			if c == '\'' {
+				inSingle = false
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		case c == '$':
+			value, n := p.expand(line[i:])
+			word.WriteString(value)
+			i += n - 1
+			inWord = true
+		case inDouble:
+			if c == '"' {
+				inDouble = false
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'':
+			inSingle, inWord = true, true
+		case c == '"':
+			inDouble, inWord = true, true
+		case c == '#' && !inWord:
+			endCommand()
+			return commands
+		case c == ';':
+			endCommand()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// expand resolves the variable reference at the start of s ($VAR, ${VAR},
+// ${VAR:-default} or ${VAR-default}) and returns its value and the number
+// of bytes consumed. References to variables the file doesn't set are
+// returned as written, with file variables in their default resolved, so
+// a converted layout doesn't bake in the environment it was converted in.
+func (p *tmuxifierParser) expand(s string) (string, int) {
+	if len(s) < 2 {
+		return "$", 1
+	}
+
+	if s[1] != '{' {
+		end := 1
+		for end < len(s) && (s[end] == '_' || isAlnum(s[end])) {
+			end++
+		}
+		if end == 1 {
+			return "$", 1
+		}
+		if value, ok := p.vars[s[1:end]]; ok {
+			return value, end
+		}
+		return s[:end], end
+	}
+
+	// Find the matching brace; defaults may nest further references
+	depth, end := 0, -1
+	for i := 1; i < len(s); i++ {
+		if s[i] == '{' {
+			depth++
+		} else if s[i] == '}' {
+			depth--
+			if depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return s, len(s)
+	}
+
+	body := s[2:end]
+	name, op, def := body, "", ""
+	if i := strings.Index(body, ":-"); i >= 0 {
+		name, op, def = body[:i], ":-", body[i+2:]
+	} else if i := strings.Index(body, "-"); i >= 0 {
+		name, op, def = body[:i], "-", body[i+1:]
+	}
+
+	value, set := p.vars[name]
+	switch {
+	case set && (op != ":-" || value != ""):
+		return value, end + 1
+	case set:
+		return p.expandAll(strings.Trim(def, `"'`)), end + 1
+	case op != "":
+		return "${" + name + op + p.expandAll(def) + "}", end + 1
+	}
+	return s[:end+1], end + 1
+}
+
+// expandAll expands every variable reference in s
+func (p *tmuxifierParser) expandAll(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' {
+			value, n := p.expand(s[i:])
+			out.WriteString(value)
+			i += n - 1
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,171 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestParseTmuxifierLayout(t *testing.T) {
+	tests := []struct {
+		name         string
+		base         int
+		paneBase     int
+		layout       string
+		want         []SpecWindow
+		selectWindow string
+		warnings     []string
+	}{
+		{
+			name: "splits and commands",
+			layout: `session_root "~/code/web"
+if initialize_session "web"; then
+  new_window "editor"
+  split_h 40
+  run_cmd "npm run dev"
+  split_vl 10 0
+  run_cmd "make watch" 2
+  select_pane 0
+  run_cmd "nvim"
+fi
+finalize_and_go_to_session
+`,
+			want: []SpecWindow{{Name: "editor", Panes: []SpecPane{
+				{Command: "nvim"},
+				{Split: "horizontal", Size: "40%", Command: "npm run dev"},
+				{Split: "vertical", Size: "10", Target: intPtr(0), Command: "make watch"},
+			}}},
+		},
+		{
+			name:     "pane-base-index 1",
+			paneBase: 1,
+			layout: `new_window "editor"
+split_v 50 1
+run_cmd "htop" 2
+select_pane 1
+run_cmd "nvim"
+select_pane 0
+`,
+			want: []SpecWindow{{Name: "editor", Panes: []SpecPane{
+				{Command: "nvim"},
+				{Split: "vertical", Size: "50%", Target: intPtr(0), Command: "htop"},
+			}}},
+			warnings: []string{"web.session.sh:6: pane 0: no such pane, panes are 1-2 with pane-base-index 1"},
+		},
+		{
+			name: "line continuations",
+			layout: `new_window \
+  "logs" \
+  "tail -f log/dev.log"
+run_cmd "echo a\\"
+`,
+			want: []SpecWindow{{Name: "logs", Panes: []SpecPane{
+				{Command: "tail -f log/dev.log", Commands: []string{`echo a\`}}},
+			}},
+		},
+		{
+			name: "variables and defaults",
+			layout: `PORT=3000
+ROOT=$HOME/code
+NAME=
+window_root "$ROOT/${APP-web}"
+new_window "${NAME:-server}" "serve --port $PORT --env ${WARPP_TEST_ENV:-dev} --cache ${CACHE:-$PORT}"
+`,
+			// Only the file's own variables are resolved, not the environment
+			want: []SpecWindow{{Name: "server", Root: "$HOME/code/${APP-web}", Panes: []SpecPane{
+				{Command: "serve --port 3000 --env ${WARPP_TEST_ENV:-dev} --cache ${CACHE:-3000}"},
+			}}},
+		},
+		{
+			name: "selected window",
+			layout: `new_window "editor"
+new_window "server"
+select_window 1
+`,
+			want: []SpecWindow{
+				{Name: "editor", Panes: []SpecPane{{}}},
+				{Name: "server", Panes: []SpecPane{{}}},
+			},
+			selectWindow: "1",
+		},
+		{
+			name: "selected window with base-index 1",
+			base: 1,
+			layout: `new_window "editor"
+new_window "server"
+select_window 2
+select_window 0
+`,
+			want: []SpecWindow{
+				{Name: "editor", Panes: []SpecPane{{}}},
+				{Name: "server", Panes: []SpecPane{{}}},
+			},
+			selectWindow: "1",
+			warnings:     []string{"web.session.sh:4: window 0: no such window, windows are 1-2 with base-index 1"},
+		},
+		{
+			name: "selected window by name",
+			layout: `new_window "editor"
+new_window "server"
+select_window "server"
+`,
+			want: []SpecWindow{
+				{Name: "editor", Panes: []SpecPane{{}}},
+				{Name: "server", Panes: []SpecPane{{}}},
+			},
+			selectWindow: "server",
+		},
+		{
+			name: "conditional commands",
+			layout: `if initialize_session "web"; then
+  new_window "editor"
+  if [ -f Procfile ]; then
+    run_cmd "foreman start"
+  else
+    run_cmd "make dev"
+  fi
+  tmux set status off
+fi
+`,
+			want: []SpecWindow{{Name: "editor", Panes: []SpecPane{
+				{Command: "foreman start", Commands: []string{"make dev"}},
+			}}},
+			warnings: []string{
+				"web.session.sh:4: run_cmd: inside an if block, applied whether or not its condition holds",
+				"web.session.sh:6: run_cmd: inside an if block, applied whether or not its condition holds",
+				"web.session.sh:8: tmux: not modeled",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", "/home/test")
+			t.Setenv("WARPP_TEST_ENV", "staging")
+			path := filepath.Join(t.TempDir(), "web.session.sh")
+			if err := os.WriteFile(path, []byte(tt.layout), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseTmuxifierLayout(path, tt.base, tt.paneBase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Spec.Name != "web" {
+				t.Errorf("name = %q, want web", got.Spec.Name)
+			}
+			if !reflect.DeepEqual(got.Spec.Windows, tt.want) {
+				t.Errorf("windows\ngot  %+v\nwant %+v", got.Spec.Windows, tt.want)
+			}
+			if got.Spec.SelectWindow != tt.selectWindow {
+				t.Errorf("select window = %q, want %q", got.Spec.SelectWindow, tt.selectWindow)
+			}
+			if !reflect.DeepEqual(got.Warnings, tt.warnings) {
+				t.Errorf("warnings\ngot  %q\nwant %q", got.Warnings, tt.warnings)
+			}
+		})
+	}
+}
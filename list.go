@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"warpp/internal/config"
+	"warpp/internal/history"
+	"warpp/internal/tmux"
+)
+
+// Groups of the session list, in display order
+const (
+	groupRecent = iota
+	groupSessions
+	groupLayouts
+)
+
+// groupTitles are the headers shown above each group
+var groupTitles = map[int]string{
+	groupRecent:   "RECENT:",
+	groupSessions: "SESSIONS:",
+	groupLayouts:  "LAYOUTS:",
+}
+
+// recentLimit is how many sessions the Recent group shows
+const recentLimit = 5
+
+// listRow is one line of the session list: a session, or one of its windows
+// or panes when the session is expanded
+type listRow struct {
+	group   int
+	session tmux.Session
+	window  *tmux.WindowInfo // set for window and pane rows
+	pane    *tmux.PaneInfo   // set for pane rows
+	score   int              // search score, when filtering
+	matched []int            // rune positions of the search query in the session name
+}
+
+// key identifies a row across reloads so the cursor can stay on it
+func (r listRow) key() string {
+	switch {
+	case r.pane != nil:
+		return fmt.Sprintf("%d/%s:%d.%d", r.group, r.session.Name, r.window.Index, r.pane.Index)
+	case r.window != nil:
+		return fmt.Sprintf("%d/%s:%d", r.group, r.session.Name, r.window.Index)
+	}
+	return fmt.Sprintf("%d/%s", r.group, r.session.Name)
+}
+
+// windowsMsg carries the windows of an expanded session
+type windowsMsg struct {
+	session string
+	windows []tmux.WindowInfo
+	err     error
+}
+
+// loadWindowsCmd lists a session's windows and panes in the background
+func loadWindowsCmd(backend tmux.Backend, sessionName string) tea.Cmd {
+	return func() tea.Msg {
+		windows, err := backend.ListWindows(context.Background(), sessionName)
+		return windowsMsg{session: sessionName, windows: windows, err: err}
+	}
+}
+
+// rows flattens the session list into selectable rows: recently used
+// sessions when enabled, running sessions followed by the windows and panes
+// of expanded ones, then layouts. While searching, only matching sessions
+// are listed, best match first in each group, and windows are left out.
+func (m simpleModel) rows() []listRow {
+	if m.query != "" {
+		var running, layouts []listRow
+		for _, session := range m.sessions {
+			row, ok := matchSession(session, m.query)
+			if !ok {
+				continue
+			}
+			if session.IsRunning {
+				row.group = groupSessions
+				running = append(running, row)
+			} else if session.IsLayout {
+				row.group = groupLayouts
+				layouts = append(layouts, row)
+			}
+		}
+		for _, group := range [][]listRow{running, layouts} {
+			sort.SliceStable(group, func(i, j int) bool {
+				return group[i].score > group[j].score
+			})
+		}
+		return append(running, layouts...)
+	}
+
+	var rows []listRow
+	if m.showRecent && m.history != nil {
+		for _, name := range m.history.Recent(recentLimit, time.Now()) {
+			// A running session wins over the layout of the same name
+			for _, session := range m.sessions {
+				if session.Name == name && (session.IsRunning || session.IsLayout) {
+					rows = append(rows, listRow{group: groupRecent, session: session})
+					break
+				}
+			}
+		}
+	}
+
+	for _, session := range m.sessions {
+		if !session.IsRunning {
+			continue
+		}
+		rows = append(rows, listRow{group: groupSessions, session: session})
+		if !m.expanded[session.Name] {
+			continue
+		}
+		windows := m.windows[session.Name]
+		for wi := range windows {
+			rows = append(rows, listRow{group: groupSessions, session: session, window: &windows[wi]})
+			for pi := range windows[wi].Panes {
+				rows = append(rows, listRow{group: groupSessions, session: session, window: &windows[wi], pane: &windows[wi].Panes[pi]})
+			}
+		}
+	}
+	for _, session := range m.sessions {
+		if !session.IsRunning && session.IsLayout {
+			rows = append(rows, listRow{group: groupLayouts, session: session})
+		}
+	}
+	return rows
+}
+
+// sortSessions orders running sessions before layouts, and each group by
+// name, frecency or last activity. Sessions that were never used, and
+// layouts when sorting by activity, sort by name after the others. With
+// waitingFirst, sessions with an agent waiting for input lead.
+func sortSessions(sessions []tmux.Session, mode string, waitingFirst bool, h *history.History) {
+	now := time.Now()
+	sort.SliceStable(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if a.IsRunning != b.IsRunning {
+			return a.IsRunning
+		}
+		if waitingFirst {
+			if wa, wb := isWaiting(a), isWaiting(b); wa != wb {
+				return wa
+			}
+		}
+		switch {
+		case mode == config.SortByFrecency && h != nil:
+			if sa, sb := h.Score(a.Name, now), h.Score(b.Name, now); sa != sb {
+				return sa > sb
+			}
+		case mode == config.SortByActivity:
+			if !a.Activity.Equal(b.Activity) {
+				return a.Activity.After(b.Activity)
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// toggleSort cycles through the sort modes, keeping the cursor on the
+// selected row
+func (m *simpleModel) toggleSort() {
+	selected, _ := m.selectedRow()
+	next := 0
+	for i, mode := range config.SortModes {
+		if mode == m.sortMode {
+			next = (i + 1) % len(config.SortModes)
+		}
+	}
+	m.sortMode = config.SortModes[next]
+	sortSessions(m.sessions, m.sortMode, m.waitingFirst, m.history)
+	m.moveCursorTo(selected.key())
+}
+
+// toggleWaitingFirst switches listing sessions that wait for input first,
+// keeping the cursor on the selected row
+func (m *simpleModel) toggleWaitingFirst() {
+	selected, _ := m.selectedRow()
+	m.waitingFirst = !m.waitingFirst
+	sortSessions(m.sessions, m.sortMode, m.waitingFirst, m.history)
+	m.moveCursorTo(selected.key())
+}
+
+// isWaiting reports whether an agent in the session waits for input
+func isWaiting(session tmux.Session) bool {
+	agent, ok := session.TopAgent()
+	return ok && agent.State == tmux.AgentWaiting
+}
+
+// matchSession fuzzy-matches query against a session's name, description,
+// project root and project type, keeping the best score. Name matches rank
+// above the others and are the only ones highlighted.
+func matchSession(session tmux.Session, query string) (listRow, bool) {
+	row := listRow{session: session}
+	found := false
+	if score, positions, ok := fuzzyMatch(query, session.Name); ok {
+		row.score = score + nameMatchBonus
+		row.matched = positions
+		found = true
+	}
+	for _, field := range []string{session.Description, session.ProjectRoot, session.ProjectType} {
+		if score, _, ok := fuzzyMatch(query, field); ok && (!found || score > row.score) {
+			row.score = score
+			row.matched = nil
+			found = true
+		}
+	}
+	return row, found
+}
+
+// nameMatchBonus ranks a match in the session name above the same match in
+// its other fields
+const nameMatchBonus = 32
+
+// bestMatch returns the index of the highest-scoring row
+func bestMatch(rows []listRow) int {
+	best := 0
+	for i, row := range rows {
+		if row.score > rows[best].score {
+			best = i
+		}
+	}
+	return best
+}
+
+// selectedRow returns the row under the cursor
+func (m simpleModel) selectedRow() (listRow, bool) {
+	rows := m.rows()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return listRow{}, false
+	}
+	return rows[m.cursor], true
+}
+
+// moveCursorTo puts the cursor on the row with key, if it's still listed,
+// and otherwise keeps it in range
+func (m *simpleModel) moveCursorTo(key string) {
+	rows := m.rows()
+	for i, row := range rows {
+		if row.key() == key {
+			m.cursor = i
+			return
+		}
+	}
+	if m.cursor >= len(rows) {
+		m.cursor = max(len(rows)-1, 0)
+	}
+}
+
+// expand shows the windows and panes of the selected running session
+func (m *simpleModel) expand() tea.Cmd {
+	row, ok := m.selectedRow()
+	if !ok || row.group != groupSessions || row.window != nil {
+		return nil
+	}
+	m.expanded[row.session.Name] = true
+	return loadWindowsCmd(m.backend, row.session.Name)
+}
+
+// collapse hides the windows of the selected session, moving the cursor up
+// to the session when it was on one of them
+func (m *simpleModel) collapse() {
+	row, ok := m.selectedRow()
+	if !ok || !m.expanded[row.session.Name] {
+		return
+	}
+	delete(m.expanded, row.session.Name)
+	m.moveCursorTo(listRow{group: row.group, session: row.session}.key())
+}
+
+// treeLine formats a window or pane row of an expanded session. Like tmux's
+// status line, * marks the current window and the active pane.
+func treeLine(row listRow) string {
+	if row.pane != nil {
+		line := fmt.Sprintf("%d %s", row.pane.Index, row.pane.Command)
+		if row.pane.Active {
+			line += "*"
+		}
+		return line + " " + shortenHome(row.pane.Path)
+	}
+	line := fmt.Sprintf("%d: %s", row.window.Index, row.window.Name)
+	if row.window.Active {
+		line += "*"
+	}
+	return line
+}
+
+// paneAgent returns the agent running in a pane of the session
+func paneAgent(session tmux.Session, pane *tmux.PaneInfo) (tmux.AgentState, bool) {
+	if pane == nil {
+		return tmux.AgentState{}, false
+	}
+	for _, agent := range session.Agents {
+		if agent.PaneID == pane.ID {
+			return agent, true
+		}
+	}
+	return tmux.AgentState{}, false
+}
+
+// agentIcon renders an agent's icon in its color, animated while it's
+// executing. An agent waiting for input alternates with an attention icon.
+func (m simpleModel) agentIcon(agent tmux.AgentState) string {
+	cfg := m.agents[agent.Agent]
+	icon := cfg.Icon
+	if icon == "" {
+		icon = "●"
+	}
+	switch agent.State {
+	case tmux.AgentExecuting:
+		icon = agentSpinnerFrames[m.spinnerFrame]
+	case tmux.AgentWaiting:
+		if m.spinnerFrame < len(agentSpinnerFrames)/2 {
+			return m.styles.Warning.Bold(true).Render("⚠")
+		}
+	}
+	style := lipgloss.NewStyle()
+	if cfg.Color != "" {
+		style = style.Foreground(lipgloss.Color(cfg.Color))
+	}
+	return style.Render(icon)
+}
+
+// relativeTime formats how long ago t was in at most three cells, e.g. 5m
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dw", int(d.Hours()/24/7))
+	}
+	return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+}
+
+// renderMatches renders text with the runes at positions in highlight and
+// the rest in base
+func renderMatches(text string, positions []int, base, highlight lipgloss.Style) string {
+	if len(positions) == 0 {
+		return base.Render(text)
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+
+	var out strings.Builder
+	var run []rune
+	runMatched := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runMatched {
+			out.WriteString(highlight.Render(string(run)))
+		} else {
+			out.WriteString(base.Render(string(run)))
+		}
+		run = run[:0]
+	}
+	for i, r := range []rune(text) {
+		if matched[i] != runMatched {
+			flush()
+			runMatched = matched[i]
+		}
+		run = append(run, r)
+	}
+	flush()
+	return out.String()
+}
+
+// launchRow attaches to the target of a window or pane row, selecting that
+// window and pane first. On success it does not return.
+func launchRow(row listRow) error {
+	pane := -1
+	if row.pane != nil {
+		pane = row.pane.Index
+	}
+	if err := tmux.SelectTarget(context.Background(), row.session.Name, row.window.Index, pane); err != nil {
+		return err
+	}
+	return attachSession(row.session.Name)
+}
+
+// renderRow renders one row of the session list, width cells wide, with
+// session names padded to nameWidth
+func (m simpleModel) renderRow(row listRow, selected bool, width, nameWidth int) string {
+	session := row.session
+
+	// Rows are rendered piecewise so icons and search matches keep their
+	// own colors
+	cursor := " "
+	style := m.styles.Normal
+	highlight := m.styles.Title.Underline(true)
+	if selected {
+		cursor = "→"
+		style = m.styles.Selected
+		highlight = style.Underline(true)
+	}
+	name := renderMatches(session.Name, row.matched, style, highlight)
+
+	var line string
+	switch {
+	case row.window != nil:
+		indent := "   "
+		if row.pane != nil {
+			indent = "     "
+			if !selected {
+				style = m.styles.Muted
+			}
+		}
+		text := truncateRunes(treeLine(row), width-10-len(indent))
+		if agent, ok := paneAgent(session, row.pane); ok {
+			// Agent icon in place of the first indent column
+			line = style.Render(fmt.Sprintf(" %s %s", cursor, indent[:len(indent)-2])) + m.agentIcon(agent) + style.Render(" "+text)
+		} else {
+			line = style.Render(fmt.Sprintf(" %s %s%s", cursor, indent, text))
+		}
+	case session.IsRunning:
+		// Show the agent that most needs attention, if any
+		icon := style.Render(session.Icon) // Default icon (•)
+		if agent, ok := session.TopAgent(); ok {
+			icon = m.agentIcon(agent)
+		}
+
+		// Last activity, window count, and markers for attached clients
+		// and windows with alerts
+		columnStyle := m.styles.Muted
+		if selected {
+			columnStyle = style
+		}
+		nameWidth := min(nameWidth, width-22)
+		shown := runewidth.Truncate(session.Name, nameWidth, "…")
+		if shown != session.Name {
+			name = renderMatches(shown, row.matched, style, highlight)
+		}
+		padding := strings.Repeat(" ", max(nameWidth-runewidth.StringWidth(shown), 0))
+		columns := fmt.Sprintf(" %3s %2dw ", relativeTime(session.Activity, time.Now()), session.Windows)
+		attached := " "
+		if session.Attached > 0 {
+			attached = "◉"
+		}
+		alert := style.Render(" ")
+		if session.Alerts != "" && selected {
+			alert = style.Render("!")
+		} else if session.Alerts != "" {
+			alert = m.styles.Warning.Render("!")
+		}
+
+		line = style.Render(fmt.Sprintf(" %s ", cursor)) + icon + style.Render(" ") + name +
+			style.Render(padding) + columnStyle.Render(columns) + columnStyle.Render(attached) + alert
+	default:
+		padding := strings.Repeat(" ", max(nameWidth-runewidth.StringWidth(session.Name), 0))
+		line = style.Render(fmt.Sprintf(" %s %s ", cursor, session.Icon)) + name +
+			style.Render(padding+" "+layoutSummary(session))
+	}
+
+	if selected {
+		line = style.Render(" ") + line + style.Render(" ")
+	}
+	return line
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"warpp/internal/themes"
+	"warpp/internal/tmux"
+)
+
+func TestRenderRowAlignsWideNames(t *testing.T) {
+	m := simpleModel{styles: themes.GetTheme("").Styles()}
+	for _, running := range []bool{true, false} {
+		var widths []int
+		for _, name := range []string{"web", "café", "日本語", "🚀x"} {
+			session := tmux.Session{Name: name, IsRunning: running, IsLayout: !running, Icon: "•"}
+			widths = append(widths, lipgloss.Width(m.renderRow(listRow{session: session}, false, 60, 6)))
+		}
+		for _, w := range widths[1:] {
+			if w != widths[0] {
+				t.Errorf("running %v: row widths %v differ", running, widths)
+				break
+			}
+		}
+	}
+
+	// Names wider than the column are cut to it
+	session := tmux.Session{Name: "日本語のセッション", IsRunning: true, Icon: "•"}
+	short := tmux.Session{Name: "web", IsRunning: true, Icon: "•"}
+	got := lipgloss.Width(m.renderRow(listRow{session: session}, false, 30, 20))
+	want := lipgloss.Width(m.renderRow(listRow{session: short}, false, 30, 20))
+	if got != want {
+		t.Errorf("truncated row is %d cells wide, want %d", got, want)
+	}
+}
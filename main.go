@@ -1,40 +1,54 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 
 	"warpp/internal/config"
+	"warpp/internal/daemon"
+	"warpp/internal/history"
+	"warpp/internal/hooks"
+	"warpp/internal/rules"
+	"warpp/internal/snapshot"
 	"warpp/internal/themes"
 	"warpp/internal/tmux"
 )
 
-// Claude's spinner frames for executing status
-var claudeSpinnerFrames = []string{"·", "✻", "✽", "✶", "✳", "✢"}
+// Spinner frames for agents that are executing
+var agentSpinnerFrames = []string{"·", "✻", "✽", "✶", "✳", "✢"}
 
 // ansiRegex matches ANSI escape sequences
 var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
-// truncateWithANSI truncates a string to maxWidth visible characters, preserving ANSI codes
+// truncateWithANSI truncates a string to maxWidth visible cells, preserving ANSI
+// codes. A truncated string ends with an ellipsis within maxWidth.
 func truncateWithANSI(s string, maxWidth int) string {
 	if maxWidth <= 0 {
 		return ""
 	}
+	if lipgloss.Width(s) <= maxWidth {
+		return s
+	}
 
 	var result strings.Builder
 	visibleLen := 0
 	i := 0
 
-	for i < len(s) && visibleLen < maxWidth {
+	for i < len(s) {
 		// Check if we're at an ANSI escape sequence
 		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
 			// Find the end of the escape sequence
@@ -50,26 +64,23 @@ func truncateWithANSI(s string, maxWidth int) string {
 			}
 		}
 
-		// Regular character
-		result.WriteByte(s[i])
-		visibleLen++
-		i++
-	}
-
-	// If we truncated, add ellipsis and reset code
-	if visibleLen >= maxWidth && i < len(s) {
-		// Check if there's more visible content
-		remaining := s[i:]
-		stripped := ansiRegex.ReplaceAllString(remaining, "")
-		if len(stripped) > 0 {
-			result.WriteString("…\x1b[0m")
+		// Regular character; leave room for the ellipsis
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runewidth.RuneWidth(r)
+		if visibleLen+w > maxWidth-1 {
+			break
 		}
+		result.WriteRune(r)
+		visibleLen += w
+		i += size
 	}
 
+	result.WriteString("…\x1b[0m")
 	return result.String()
 }
 
 type simpleModel struct {
+	backend        tmux.Backend // tmux server and process table the UI reads from
 	sessions       []tmux.Session
 	cursor         int
 	theme          themes.Theme
@@ -79,14 +90,52 @@ type simpleModel struct {
 	width          int
 	height         int
 	confirmingKill bool
-	spinnerFrame   int // Current frame of Claude spinner animation
+	trash          int    // killed sessions kept for undo
+	lastKilled     string // session killed in this run, until it's restored
+	working        string // kill or undo running in the background, e.g. "Killing api…"
+	spinnerFrame   int    // Current frame of Claude spinner animation
 	// Worktree flow states
-	worktreeInputStep   int    // 0=none, 1=session name, 2=branch name
-	worktreeSessionName string // text input for session name
-	worktreeBranchName  string // text input for branch name
-	worktreeLayoutName  string // original layout name for worktree
-	worktreeProjectRoot string // base path for worktree creation
-	errorMessage        string // error message to display
+	worktreeInputStep   int          // 0=none, 1=session name, 2=branch name
+	worktreeSessionName string       // text input for session name
+	worktreeBranchName  string       // text input for branch name
+	worktreeLayout      tmux.Session // original layout for worktree
+	worktreeProjectRoot string       // base path for worktree creation
+	errorMessage        string       // error message to display
+	// Preview capture pipeline
+	previews      map[string]sessionPreview // session name -> last captured preview
+	previewSeq    int                       // bumped whenever the selection changes
+	previewCancel context.CancelFunc        // cancels the in-flight capture
+	// Session tree
+	expanded map[string]bool              // running sessions showing their windows and panes
+	windows  map[string][]tmux.WindowInfo // windows of expanded sessions
+	// Search
+	searching bool   // typing a search query
+	query     string // fuzzy filter over the list
+	// Ordering
+	sortMode     string           // one of config.SortModes
+	showRecent   bool             // show the Recent group
+	waitingFirst bool             // list sessions waiting for input first
+	history      *history.History // launch history for frecency
+	// Agents
+	agents map[string]config.Agent // agent name -> icon and color
+	rules  *rules.Engine           // runs actions when agents change state
+	daemon *daemon.Client          // serves sessions when warpp daemon runs
+	events <-chan daemon.Event     // the daemon's events, while subscribed
+	// Popup mode: no header, and the panels fill the window
+	compact bool
+	// Inline mode: a fixed-height list below the prompt, without the
+	// alternate screen
+	inline        bool
+	inlineHeight  int          // list rows
+	inlinePreview bool         // one line of the selected session under the list
+	quitting      bool         // erase the list on the final render
+	pending       func() error // launch to run after exiting inline mode
+	// Demo mode: an in-memory tmux, where nothing is launched or killed
+	demo bool
+	// Pick mode: Enter returns the selected row instead of attaching
+	picking bool
+	only    string   // pickRunning or pickLayouts limits the candidates
+	picked  *listRow // the choice, once made
 }
 
 // tickMsg is sent periodically to update the spinner animation
@@ -98,16 +147,84 @@ func tickCmd() tea.Cmd {
 	})
 }
 
+// sessionsRefreshInterval is how often the session list is reloaded, so
+// activity, attached clients and new sessions show up
+const sessionsRefreshInterval = 5 * time.Second
+
+// sessionsTickMsg asks for a reload of the session list
+type sessionsTickMsg struct{}
+
+func sessionsTickCmd() tea.Cmd {
+	return tea.Tick(sessionsRefreshInterval, func(time.Time) tea.Msg {
+		return sessionsTickMsg{}
+	})
+}
+
+// sessionsErrMsg reports that the session list couldn't be loaded
+type sessionsErrMsg struct {
+	err error
+}
+
+// loadSessionsCmd loads running sessions and layouts in the background: from
+// the daemon when it's running, and otherwise from tmux, feeding their
+// agent states to the rules engine
+func loadSessionsCmd(backend tmux.Backend, engine *rules.Engine, client *daemon.Client) tea.Cmd {
+	return func() tea.Msg {
+		if sessions, err := client.List(context.Background()); err == nil {
+			return sessions
+		}
+		sessions, err := tmux.GetAllSessions(context.Background(), backend)
+		if err != nil {
+			return sessionsErrMsg{err}
+		}
+		if engine != nil {
+			engine.Observe(context.Background(), sessions, time.Now())
+		}
+		return sessions
+	}
+}
+
+// daemonEventsMsg carries a subscription to the daemon's events
+type daemonEventsMsg struct {
+	events <-chan daemon.Event
+	cancel context.CancelFunc
+}
+
+// daemonEventMsg is the next event of the subscription, or its end when ok
+// is false
+type daemonEventMsg struct {
+	event daemon.Event
+	ok    bool
+}
+
+// subscribeCmd subscribes to the daemon's events in the background. When
+// the daemon isn't running it does nothing and the list keeps polling.
+func subscribeCmd(client *daemon.Client) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := client.Subscribe(ctx)
+		if err != nil {
+			cancel()
+			return nil
+		}
+		return daemonEventsMsg{events: events, cancel: cancel}
+	}
+}
+
+// nextEventCmd waits for the daemon's next event
+func nextEventCmd(events <-chan daemon.Event) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		return daemonEventMsg{event: event, ok: ok}
+	}
+}
+
 func (m simpleModel) Init() tea.Cmd {
 	return tea.Batch(
-		func() tea.Msg {
-			sessions, err := tmux.GetAllSessions()
-			if err != nil {
-				return fmt.Sprintf("Error: %v", err)
-			}
-			return sessions
-		},
+		loadSessionsCmd(m.backend, m.rules, m.daemon),
+		subscribeCmd(m.daemon),
 		tickCmd(),
+		sessionsTickCmd(),
 	)
 }
 
@@ -118,20 +235,121 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 	case []tmux.Session:
-		m.sessions = msg
+		// Keep the cursor on the same row, and refresh the windows of
+		// sessions that are still expanded
+		selected, hadSelection := m.selectedRow()
+		m.sessions = filterSessions(msg, m.only)
+		sortSessions(m.sessions, m.sortMode, m.waitingFirst, m.history)
+		cmds := []tea.Cmd{m.schedulePreview(0)}
+		running := make(map[string]bool)
+		for _, session := range m.sessions {
+			running[session.Name] = session.IsRunning
+		}
+		for name := range m.expanded {
+			if !running[name] {
+				delete(m.expanded, name)
+				delete(m.windows, name)
+				continue
+			}
+			cmds = append(cmds, loadWindowsCmd(m.backend, name))
+		}
+		if !hadSelection && m.query != "" {
+			// First load with a query from warpp pick --query: start on
+			// the best match, as typing it would
+			m.cursor = bestMatch(m.rows())
+		} else {
+			m.moveCursorTo(selected.key())
+		}
+		return m, tea.Batch(cmds...)
+	case windowsMsg:
+		selected, _ := m.selectedRow()
+		if msg.err != nil {
+			delete(m.expanded, msg.session)
+			delete(m.windows, msg.session)
+			if !errors.Is(msg.err, tmux.ErrSessionNotFound) {
+				m.errorMessage = msg.err.Error()
+			}
+		} else {
+			m.windows[msg.session] = msg.windows
+		}
+		m.moveCursorTo(selected.key())
 		return m, nil
+	case previewTickMsg:
+		if msg.seq != m.previewSeq {
+			return m, nil
+		}
+		return m, m.startPreview()
+	case previewMsg:
+		if msg.seq != m.previewSeq {
+			return m, nil
+		}
+		m.storePreview(msg)
+		seq := m.previewSeq
+		return m, tea.Tick(previewInterval, func(time.Time) tea.Msg {
+			return previewTickMsg{seq: seq}
+		})
 	case tickMsg:
-		m.spinnerFrame = (m.spinnerFrame + 1) % len(claudeSpinnerFrames)
+		m.spinnerFrame = (m.spinnerFrame + 1) % len(agentSpinnerFrames)
 		if len(m.asciiFrames) > 1 {
 			m.asciiFrame = (m.asciiFrame + 1) % len(m.asciiFrames)
 		}
 		return m, tickCmd()
-	case string: // error message
-		fmt.Println(msg)
-		return m, tea.Quit
+	case sessionsTickMsg:
+		if m.events != nil {
+			// The daemon pushes changes as it sees them
+			return m, sessionsTickCmd()
+		}
+		return m, tea.Batch(loadSessionsCmd(m.backend, m.rules, m.daemon), subscribeCmd(m.daemon), sessionsTickCmd())
+	case daemonEventsMsg:
+		if m.events != nil {
+			msg.cancel()
+			return m, nil
+		}
+		m.events = msg.events
+		return m, nextEventCmd(m.events)
+	case daemonEventMsg:
+		if !msg.ok {
+			// The daemon went away; poll tmux until it's back
+			m.events = nil
+			return m, loadSessionsCmd(m.backend, m.rules, m.daemon)
+		}
+		next := nextEventCmd(m.events)
+		switch msg.event.Type {
+		case daemon.EventSessions:
+			updated, cmd := m.Update(msg.event.Sessions)
+			return updated, tea.Batch(cmd, next)
+		case daemon.EventTransition:
+			// The selected session's agent changed state, so its panes did too
+			if row, ok := m.selectedRow(); ok && msg.event.Transition != nil && row.session.Name == msg.event.Transition.Session {
+				return m, tea.Batch(m.schedulePreview(0), next)
+			}
+		}
+		return m, next
+	case sessionsErrMsg:
+		m.errorMessage = msg.err.Error()
+		return m, nil
+	case killedMsg:
+		m.working = ""
+		if msg.err != nil {
+			m.errorMessage = msg.err.Error()
+		} else {
+			m.lastKilled = msg.name
+		}
+		return m, loadSessionsCmd(m.backend, m.rules, m.daemon)
+	case restoredMsg:
+		m.working = ""
+		if msg.err != nil {
+			m.errorMessage = msg.err.Error()
+		} else if m.lastKilled == msg.name {
+			m.lastKilled = ""
+		}
+		return m, loadSessionsCmd(m.backend, m.rules, m.daemon)
 	case tea.KeyMsg:
-		// Clear error message on any key
+		// Clear error message on any key; with nothing loaded there's nothing to go back to
 		if m.errorMessage != "" {
+			if len(m.sessions) == 0 {
+				return m.quit()
+			}
 			m.errorMessage = ""
 			return m, nil
 		}
@@ -159,7 +377,7 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, nil
 					}
 					// Create worktree and launch
-					worktreePath, err := tmux.CreateWorktree(m.worktreeProjectRoot, m.worktreeSessionName, m.worktreeBranchName)
+					worktreePath, err := tmux.CreateWorktree(context.Background(), m.worktreeProjectRoot, m.worktreeSessionName, m.worktreeBranchName)
 					if err != nil {
 						m.errorMessage = err.Error()
 						m.worktreeInputStep = 0
@@ -168,8 +386,11 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, nil
 					}
 					// Launch with worktree
-					launchWorktreeSession(m.worktreeLayoutName, m.worktreeSessionName, worktreePath)
-					return m, tea.Quit
+					layout, name := m.worktreeLayout, m.worktreeSessionName
+					m.worktreeInputStep = 0
+					m.worktreeSessionName = ""
+					m.worktreeBranchName = ""
+					return m.launch(func() error { return launchWorktreeSession(layout, name, worktreePath) })
 				}
 				return m, nil
 			case "backspace":
@@ -197,19 +418,12 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.confirmingKill {
 			switch msg.String() {
 			case "y", "Y", "enter":
-				if len(m.sessions) > 0 && m.cursor < len(m.sessions) {
-					selected := m.sessions[m.cursor]
+				if row, ok := m.selectedRow(); ok {
+					selected := row.session
 					if selected.IsRunning {
-						tmux.KillSession(selected.Name)
 						m.confirmingKill = false
-						// Refresh sessions list
-						return m, func() tea.Msg {
-							sessions, err := tmux.GetAllSessions()
-							if err != nil {
-								return fmt.Sprintf("Error: %v", err)
-							}
-							return sessions
-						}
+						m.working = fmt.Sprintf("Killing %s…", selected.Name)
+						return m, killSessionCmd(m.backend, m.daemon, selected.Name, m.trash)
 					}
 				}
 				m.confirmingKill = false
@@ -221,22 +435,78 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// Handle search input; navigation and Enter fall through to the list
+		if m.searching {
+			switch msg.String() {
+			case "esc":
+				selected, _ := m.selectedRow()
+				m.searching = false
+				m.query = ""
+				m.moveCursorTo(selected.key())
+				return m, m.schedulePreview(previewDebounce)
+			case "backspace":
+				if runes := []rune(m.query); len(runes) > 0 {
+					m.query = string(runes[:len(runes)-1])
+				}
+				m.cursor = bestMatch(m.rows())
+				return m, m.schedulePreview(previewDebounce)
+			case "enter", "up", "down", "ctrl+c":
+			default:
+				if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+					m.query += string(msg.Runes)
+					m.cursor = bestMatch(m.rows())
+				}
+				return m, m.schedulePreview(previewDebounce)
+			}
+		}
+
+		if m.demo {
+			switch msg.String() {
+			case "enter", "K", "n", "u":
+				m.errorMessage = demoMessage
+				return m, nil
+			}
+		}
+
+		if m.picking {
+			switch msg.String() {
+			case "enter":
+				if row, ok := m.selectedRow(); ok {
+					m.picked = &row
+					return m.quit()
+				}
+				return m, nil
+			case "esc":
+				return m.quit()
+			case "K", "n", "u":
+				// Nothing is killed or created while picking
+				return m, nil
+			}
+		}
+
+		prevCursor := m.cursor
 		switch msg.String() {
 		case "q", "ctrl+c":
-			return m, tea.Quit
+			return m.quit()
 		case "enter":
-			if len(m.sessions) > 0 && m.cursor < len(m.sessions) {
-				selected := m.sessions[m.cursor]
-				if selected.IsRunning {
+			if row, ok := m.selectedRow(); ok {
+				selected := row.session
+				if row.window != nil {
+					// Attach to the exact window or pane
+					return m.launch(func() error { return launchRow(row) })
+				} else if selected.IsRunning {
 					// Attach to running session
-					launchSession(selected.Name)
-					return m, tea.Quit
+					return m.launch(func() error { return launchSession(selected) })
 				} else if selected.IsLayout {
 					// Check if session with same name is already running
-					runningNames := tmux.GetRunningSessionNames()
+					running, err := m.backend.ListSessions(context.Background())
+					if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+						m.errorMessage = err.Error()
+						return m, nil
+					}
 					isAlreadyRunning := false
-					for _, name := range runningNames {
-						if name == selected.Name {
+					for _, info := range running {
+						if info.Name == selected.Name {
 							isAlreadyRunning = true
 							break
 						}
@@ -248,19 +518,18 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.errorMessage = "Layout must have session_root defined to create worktree sessions."
 							return m, nil
 						}
-						if !tmux.IsGitRepo(selected.ProjectRoot) {
+						if !tmux.IsGitRepo(context.Background(), selected.ProjectRoot) {
 							m.errorMessage = "Project must be a git repository. Run 'git init' first."
 							return m, nil
 						}
 						m.worktreeInputStep = 1
-						m.worktreeLayoutName = selected.Name
+						m.worktreeLayout = selected
 						m.worktreeProjectRoot = selected.ProjectRoot
 						m.worktreeSessionName = selected.Name + "-"
 						return m, nil
 					}
 					// Normal layout launch
-					launchSession(selected.Name)
-					return m, tea.Quit
+					return m.launch(func() error { return launchSession(selected) })
 				}
 			}
 		case "up":
@@ -268,31 +537,131 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor--
 			}
 		case "down", "j":
-			if m.cursor < len(m.sessions)-1 {
+			if m.cursor < len(m.rows())-1 {
 				m.cursor++
 			}
+		case "right", "l":
+			if cmd := m.expand(); cmd != nil {
+				return m, cmd
+			}
+		case "left", "h":
+			m.collapse()
+		case "/":
+			m.searching = true
+			return m, nil
+		case "s":
+			m.toggleSort()
+		case "w":
+			m.toggleWaitingFirst()
+		case "p":
+			if m.inline {
+				m.inlinePreview = !m.inlinePreview
+			}
 		case "k":
 			// Uppercase K triggers kill, lowercase k moves up
 			if m.cursor > 0 {
 				m.cursor--
 			}
 		case "K":
-			// Kill session - only for running sessions
-			if len(m.sessions) > 0 && m.cursor < len(m.sessions) {
-				selected := m.sessions[m.cursor]
+			// Kill session - only for running sessions, from the session's own row
+			if row, ok := m.selectedRow(); ok && row.window == nil && m.working == "" {
+				selected := row.session
 				if selected.IsRunning {
 					m.confirmingKill = true
 				}
 			}
+		case "u":
+			// Bring back the session killed in this run, as it was when
+			// killed; older kills are left to warpp trash restore
+			if m.lastKilled != "" && m.working == "" {
+				m.working = fmt.Sprintf("Restoring %s…", m.lastKilled)
+				return m, undoKillCmd(m.backend, m.lastKilled)
+			}
 		case "n":
-			launchNewSession()
-			return m, tea.Quit
+			return m.launch(launchNewSession)
+		}
+
+		if m.cursor != prevCursor {
+			return m, m.schedulePreview(previewDebounce)
 		}
 	}
 	return m, nil
 }
 
+// quit ends the program, erasing the list first in inline mode
+func (m simpleModel) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// launch runs start, which attaches to or creates a session, and quits. In
+// inline mode start runs once the program has exited so the list is erased
+// first and its errors go to stderr.
+func (m simpleModel) launch(start func() error) (tea.Model, tea.Cmd) {
+	if m.inline {
+		m.pending = start
+		return m.quit()
+	}
+	if err := start(); err != nil {
+		m.errorMessage = err.Error()
+		return m, nil
+	}
+	return m.quit()
+}
+
+// footerKeys returns the keybinding hints for the current mode
+func (m simpleModel) footerKeys() string {
+	if m.searching {
+		action := "Launch"
+		if m.picking {
+			action = "Pick"
+		}
+		return fmt.Sprintf("Type to filter  •  ↑/↓ Navigate  •  Enter %s  •  Esc Cancel", action)
+	}
+	if m.picking {
+		return fmt.Sprintf("↑/↓ Navigate  •  →/← Expand  •  / Search  •  s Sort: %s  •  Enter Pick  •  Esc Cancel", m.sortMode)
+	}
+	waiting := "off"
+	if m.waitingFirst {
+		waiting = "on"
+	}
+	// Offer the undo first so it survives truncation
+	undo := ""
+	if m.working != "" {
+		undo = m.working + "  •  "
+	} else if m.lastKilled != "" {
+		undo = fmt.Sprintf("u Undo kill of %s  •  ", m.lastKilled)
+	}
+	if m.inline {
+		return fmt.Sprintf("%s↑/↓ Navigate  •  →/← Expand  •  / Search  •  s Sort: %s  •  p Preview  •  Enter Launch  •  K Kill  •  n New  •  q Quit", undo, m.sortMode)
+	}
+	return fmt.Sprintf("%s↑/↓ Navigate  •  →/← Expand  •  / Search  •  s Sort: %s  •  w Waiting first: %s  •  Enter Launch  •  K Kill  •  n New  •  q Quit", undo, m.sortMode, waiting)
+}
+
+// panelHeight returns the height of the list and preview panels for the
+// current terminal size
+func (m simpleModel) panelHeight() int {
+	panelHeight := 15
+	if m.compact && m.height > 0 {
+		// Borders, padding, the blank line and the footer take 6 rows
+		return max(m.height-6, 8)
+	}
+	if m.height > 0 {
+		panelHeight = m.height - 20
+		if panelHeight < 8 {
+			panelHeight = 8
+		}
+		if panelHeight > 30 {
+			panelHeight = 30
+		}
+	}
+	return panelHeight
+}
+
 func (m simpleModel) View() string {
+	if m.inline {
+		return m.inlineView()
+	}
 	// ASCII art header - using current animation frame
 	currentArt := ""
 	if len(m.asciiFrames) > 0 {
@@ -300,6 +669,15 @@ func (m simpleModel) View() string {
 	}
 	header := m.styles.Title.Render(currentArt)
 
+	// withHeader stacks the header above the given parts, leaving it out in
+	// compact mode
+	withHeader := func(parts ...string) string {
+		if !m.compact {
+			parts = append([]string{header, ""}, parts...)
+		}
+		return lipgloss.JoinVertical(lipgloss.Center, parts...)
+	}
+
 	if len(m.sessions) == 0 {
 		loadingBox := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
@@ -308,8 +686,31 @@ func (m simpleModel) View() string {
 			Align(lipgloss.Center).
 			Render("Loading sessions...")
 
-		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
-			lipgloss.JoinVertical(lipgloss.Center, header, "", loadingBox))
+		if m.errorMessage != "" {
+			loadingBox = lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color(m.theme.Error)).
+				Padding(1, 3).
+				Align(lipgloss.Center).
+				Render(lipgloss.JoinVertical(lipgloss.Center,
+					m.errorMessage,
+					"",
+					lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Muted)).Render("Press any key to exit"),
+				))
+		}
+
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, withHeader(loadingBox))
+	}
+
+	// Responsive panel widths
+	listWidth := 35
+	previewWidth := 50
+	if m.compact && m.width > 0 {
+		listWidth = min(max(m.width/3, 24), 40)
+		previewWidth = m.width - listWidth - 10
+	} else if m.width > 0 && m.width < 100 {
+		listWidth = 30
+		previewWidth = m.width - listWidth - 10
 	}
 
 	// Create sessions list with consistent formatting
@@ -318,105 +719,53 @@ func (m simpleModel) View() string {
 	// Calculate max name width for alignment
 	maxNameWidth := 0
 	for _, session := range m.sessions {
-		if len(session.Name) > maxNameWidth {
-			maxNameWidth = len(session.Name)
-		}
-	}
-
-	// Separate running sessions and layouts
-	var runningSessions, layouts []tmux.Session
-	for _, session := range m.sessions {
-		if session.IsRunning {
-			runningSessions = append(runningSessions, session)
-		} else if session.IsLayout {
-			layouts = append(layouts, session)
-		}
+		maxNameWidth = max(maxNameWidth, runewidth.StringWidth(session.Name))
 	}
 
-	// Track cursor position across both groups
-	cursorIdx := 0
-
-	// Orange style for Claude icons
-	orangeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF8C00"))
-
-	// Render running sessions first
-	if len(runningSessions) > 0 {
-		items = append(items, m.styles.Muted.Render("SESSIONS:"))
-		for _, session := range runningSessions {
-			cursor := " "
-			style := m.styles.Normal
-
-			if cursorIdx == m.cursor {
-				cursor = "→"
-				style = m.styles.Selected.Padding(0, 1)
+	// Render running sessions (with any expanded windows and panes) first,
+	// then layouts
+	rows := m.rows()
+	cursorLine := 0
+	for i, row := range rows {
+		if i == 0 || row.group != rows[i-1].group {
+			if i > 0 {
+				items = append(items, "") // Add spacing between sections
 			}
-			cursorIdx++
-
-			// Determine icon based on Claude status
-			var icon string
-			switch session.ClaudeStatus {
-			case "executing":
-				icon = orangeStyle.Render(claudeSpinnerFrames[m.spinnerFrame])
-			case "idle":
-				icon = orangeStyle.Render("●")
-			default:
-				icon = session.Icon // Default icon (•)
-			}
-
-			line := fmt.Sprintf(" %s %s %s",
-				cursor,
-				icon,
-				session.Name)
-
-			items = append(items, style.Render(line))
+			items = append(items, m.styles.Muted.Render(groupTitles[row.group]))
 		}
-	}
 
-	// Render layouts
-	if len(layouts) > 0 {
-		if len(runningSessions) > 0 {
-			items = append(items, "") // Add spacing between sections
-		}
-		items = append(items, m.styles.Muted.Render("LAYOUTS:"))
-		for _, session := range layouts {
-			cursor := " "
-			style := m.styles.Normal
-
-			if cursorIdx == m.cursor {
-				cursor = "→"
-				style = m.styles.Selected.Padding(0, 1)
-			}
-			cursorIdx++
-
-			line := fmt.Sprintf(" %s %s %s",
-				cursor,
-				session.Icon,
-				session.Name)
-
-			items = append(items, style.Render(line))
+		if i == m.cursor {
+			cursorLine = len(items)
 		}
-	}
-
-	// Create bordered content box with responsive width
-	listWidth := 35
-	previewWidth := 50
-	if m.width > 0 && m.width < 100 {
-		listWidth = 30
-		previewWidth = m.width - listWidth - 10
+		items = append(items, m.renderRow(row, i == m.cursor, listWidth, maxNameWidth))
 	}
 
 	// Calculate fixed height for both panels
-	panelHeight := 15
-	if m.height > 0 {
-		panelHeight = m.height - 20
-		if panelHeight < 8 {
-			panelHeight = 8
+	panelHeight := m.panelHeight()
+	listHeight := panelHeight
+
+	// Search query above the list
+	var searchLines []string
+	if m.searching || m.query != "" {
+		query := m.query
+		if m.searching {
+			query += "▏"
 		}
-		if panelHeight > 30 {
-			panelHeight = 30
+		searchLines = []string{m.styles.Title.Render("/ ") + m.styles.Normal.Render(truncateRunes(query, listWidth-8)), ""}
+		listHeight -= len(searchLines)
+		if len(rows) == 0 {
+			items = []string{m.styles.Muted.Render("No matches")}
 		}
 	}
 
+	// Scroll the list to keep the cursor in view
+	if len(items) > listHeight {
+		start := min(max(cursorLine-listHeight/2, 0), len(items)-listHeight)
+		items = items[start : start+listHeight]
+	}
+	items = append(searchLines, items...)
+
+	// Create bordered content box
 	contentBox := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color(m.theme.Border)).
@@ -427,65 +776,58 @@ func (m simpleModel) View() string {
 
 	// Build preview panel for running sessions
 	var previewBox string
-	if len(m.sessions) > 0 && m.cursor < len(m.sessions) {
-		selected := m.sessions[m.cursor]
+	if row, ok := m.selectedRow(); ok {
+		selected := row.session
 		if selected.IsRunning {
-			// Get all panes in the session
-			panes := tmux.GetSessionPanes(selected.Name)
-			numPanes := len(panes)
-			if numPanes == 0 {
-				numPanes = 1
-			}
+			// Render from the cached capture; the capture itself runs in the background
+			preview, captured := m.previews[selected.Name]
+			panes := preview.panes
 
 			totalPreviewHeight := panelHeight
-
-			// Calculate height per pane (account for separators between panes)
-			separatorLines := numPanes - 1
-			availableForPanes := totalPreviewHeight - separatorLines
-			heightPerPane := availableForPanes / numPanes
-			if heightPerPane < 3 {
-				heightPerPane = 3
-			}
-
 			maxLineWidth := previewWidth - 3
 			var paneContents []string
 
-			for i, pane := range panes {
-				// Capture content for this pane
-				content := tmux.CapturePaneByIndex(selected.Name, pane.Index, heightPerPane)
+			if preview.stale {
+				marker := "⚠ stale — capture failed"
+				if preview.err != nil {
+					marker = "⚠ stale — " + preview.err.Error()
+				}
+				paneContents = append(paneContents, m.styles.Warning.Render(truncateWithANSI(marker, maxLineWidth)))
+				totalPreviewHeight--
+			}
 
-				// Truncate lines (ANSI-aware)
-				lines := strings.Split(content, "\n")
-				var truncatedLines []string
-				for j, line := range lines {
-					if j >= heightPerPane {
-						break
+			// Draw panes where they sit in the window; fall back to stacking
+			// them when the backend reports no geometry
+			if layout := m.renderLiveLayout(panes, maxLineWidth, totalPreviewHeight); layout != "" {
+				paneContents = append(paneContents, layout)
+			} else {
+				// Calculate height per pane (account for separators between panes)
+				heightPerPane := paneCaptureHeight(totalPreviewHeight, len(panes))
+
+				for i, pane := range panes {
+					// Truncate lines (ANSI-aware)
+					lines := strings.Split(pane.Content, "\n")
+					var truncatedLines []string
+					for j, line := range lines {
+						if j >= heightPerPane {
+							break
+						}
+						truncatedLines = append(truncatedLines, truncateWithANSI(line, maxLineWidth))
 					}
-					truncatedLines = append(truncatedLines, truncateWithANSI(line, maxLineWidth))
-				}
 
-				paneContents = append(paneContents, strings.Join(truncatedLines, "\n"))
+					paneContents = append(paneContents, strings.Join(truncatedLines, "\n"))
 
-				// Add separator between panes (not after last one)
-				if i < len(panes)-1 {
-					borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Border))
-					separator := borderStyle.Render(strings.Repeat("─", maxLineWidth))
-					paneContents = append(paneContents, separator)
+					// Add separator between panes (not after last one)
+					if i < len(panes)-1 {
+						borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Border))
+						separator := borderStyle.Render(strings.Repeat("─", maxLineWidth))
+						paneContents = append(paneContents, separator)
+					}
 				}
 			}
 
-			// If no panes found, fall back to single pane capture
-			if len(panes) == 0 {
-				content := tmux.CapturePane(selected.Name, totalPreviewHeight)
-				lines := strings.Split(content, "\n")
-				var truncatedLines []string
-				for i, line := range lines {
-					if i >= totalPreviewHeight {
-						break
-					}
-					truncatedLines = append(truncatedLines, truncateWithANSI(line, maxLineWidth))
-				}
-				paneContents = append(paneContents, strings.Join(truncatedLines, "\n"))
+			if !captured {
+				paneContents = append(paneContents, m.styles.Muted.Render("Loading preview..."))
 			}
 
 			previewBox = lipgloss.NewStyle().
@@ -493,21 +835,43 @@ func (m simpleModel) View() string {
 				BorderForeground(lipgloss.Color(m.theme.Border)).
 				Padding(0, 1).
 				Width(previewWidth).
-				Height(totalPreviewHeight + 2).
+				Height(panelHeight + 2).
 				Render(lipgloss.JoinVertical(lipgloss.Left, paneContents...))
 		} else {
-			// Show layout info for non-running sessions
-			infoText := lipgloss.JoinVertical(lipgloss.Left,
-				m.styles.Title.Render(selected.Name),
-				"",
-				m.styles.Muted.Render("Layout not running"),
-				"",
-				m.styles.Normal.Render("Press Enter to launch"),
-			)
+			// Show what the layout will create for non-running sessions
+			maxLineWidth := previewWidth - 3
+			header := selected.Name
+			if selected.Source != "" {
+				header += " · " + selected.Source
+			}
+			infoLines := []string{m.styles.Title.Render(truncateRunes(header, maxLineWidth))}
+			if selected.ProjectRoot != "" {
+				infoLines = append(infoLines, m.styles.Muted.Render(truncateRunes(shortenHome(selected.ProjectRoot), maxLineWidth)))
+			}
+
+			var footerLines []string
+			for i, issue := range selected.LayoutIssues {
+				if i == 3 {
+					footerLines = append(footerLines, m.styles.Warning.Render(fmt.Sprintf("… %d more issues", len(selected.LayoutIssues)-i)))
+					break
+				}
+				footerLines = append(footerLines, m.styles.Warning.Render(truncateWithANSI("⚠ "+issue, maxLineWidth)))
+			}
+			footerLines = append(footerLines, m.styles.Normal.Render("Press Enter to launch"))
+
+			diagramHeight := panelHeight - len(infoLines) - len(footerLines) - 1
+			if selected.Layout != nil && diagramHeight >= 5 {
+				infoLines = append(infoLines, m.renderLayoutDiagram(selected.Layout, maxLineWidth, diagramHeight))
+			} else {
+				infoLines = append(infoLines, m.styles.Muted.Render(truncateRunes(selected.Description, maxLineWidth)), "")
+			}
+			infoLines = append(infoLines, footerLines...)
+
+			infoText := lipgloss.JoinVertical(lipgloss.Left, infoLines...)
 			previewBox = lipgloss.NewStyle().
 				Border(lipgloss.RoundedBorder()).
 				BorderForeground(lipgloss.Color(m.theme.Border)).
-				Padding(1, 2).
+				Padding(0, 1).
 				Width(previewWidth).
 				Height(panelHeight + 2).
 				Render(infoText)
@@ -517,10 +881,16 @@ func (m simpleModel) View() string {
 	// Combine list and preview side by side
 	mainContent := lipgloss.JoinHorizontal(lipgloss.Top, contentBox, "  ", previewBox)
 
-	// Footer with keybindings
-	footer := lipgloss.NewStyle().
-		Foreground(lipgloss.Color(m.theme.Muted)).
-		Render("↑/↓ Navigate  •  Enter Launch  •  K Kill  •  n New  •  q Quit")
+	// Footer with keybindings, squeezed onto one line in compact mode and
+	// wrapped on narrow terminals otherwise
+	keys := m.footerKeys()
+	footerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Muted))
+	if m.compact && m.width > 0 {
+		keys = truncateWithANSI(strings.ReplaceAll(keys, "  •  ", " · "), m.width)
+	} else if m.width > 4 && lipgloss.Width(keys) > m.width-4 {
+		footerStyle = footerStyle.Width(m.width - 4).Align(lipgloss.Center)
+	}
+	footer := footerStyle.Render(keys)
 
 	// Build main content
 	var content string
@@ -538,13 +908,7 @@ func (m simpleModel) View() string {
 				lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Muted)).Render("Press any key to continue"),
 			))
 
-		content = lipgloss.JoinVertical(lipgloss.Center,
-			header,
-			"",
-			mainContent,
-			"",
-			errorBox,
-		)
+		content = withHeader(mainContent, "", errorBox)
 	} else if m.worktreeInputStep > 0 {
 		// Show worktree input dialog
 		var promptText, inputValue, hint string
@@ -577,15 +941,9 @@ func (m simpleModel) View() string {
 				lipgloss.NewStyle().Foreground(lipgloss.Color(m.theme.Muted)).Render(hint),
 			))
 
-		content = lipgloss.JoinVertical(lipgloss.Center,
-			header,
-			"",
-			mainContent,
-			"",
-			inputBox,
-		)
-	} else if m.confirmingKill && len(m.sessions) > 0 && m.cursor < len(m.sessions) {
-		selected := m.sessions[m.cursor]
+		content = withHeader(mainContent, "", inputBox)
+	} else if row, ok := m.selectedRow(); m.confirmingKill && ok {
+		selected := row.session
 
 		// Create confirmation dialog
 		confirmText := fmt.Sprintf("Kill session '%s'?", selected.Name)
@@ -600,22 +958,10 @@ func (m simpleModel) View() string {
 			Align(lipgloss.Center).
 			Render(lipgloss.JoinVertical(lipgloss.Center, confirmText, "", confirmHint))
 
-		content = lipgloss.JoinVertical(lipgloss.Center,
-			header,
-			"",
-			mainContent,
-			"",
-			confirmBox,
-		)
+		content = withHeader(mainContent, "", confirmBox)
 	} else {
 		// Combine everything with proper spacing
-		content = lipgloss.JoinVertical(lipgloss.Center,
-			header,
-			"",
-			mainContent,
-			"",
-			footer,
-		)
+		content = withHeader(mainContent, "", footer)
 	}
 
 	// Center everything on screen using actual terminal dimensions
@@ -643,6 +989,54 @@ func main() {
 		case "test-ascii":
 			handleTestASCII()
 			return
+		case "convert":
+			handleConvertCommand(os.Args[2:])
+			return
+		case "validate":
+			handleValidateCommand()
+			return
+		case "hook":
+			handleHookCommand(os.Args[2:])
+			return
+		case "pick":
+			runCLI(cmdPick, os.Args[2:])
+		case "statusline":
+			runCLI(cmdStatusline, os.Args[2:])
+		case "popup":
+			runTUI(modePopup, false)
+			return
+		case "--inline", "-i":
+			runTUI(modeInline, false)
+			return
+		case "--fullscreen":
+			runTUI(modeFullscreen, false)
+			return
+		case "--demo":
+			runTUI("", true)
+			return
+		case "install-tmux-bindings":
+			runCLI(cmdInstallTmuxBindings, os.Args[2:])
+		case "snapshot":
+			runCLI(cmdSnapshot, os.Args[2:])
+		case "restore":
+			runCLI(cmdRestore, os.Args[2:])
+		case "trash":
+			runCLI(cmdTrash, os.Args[2:])
+		case "daemon":
+			handleDaemonCommand(os.Args[2:])
+			return
+		case "ls":
+			runCLI(cmdLs, os.Args[2:])
+		case "attach":
+			runCLI(cmdAttach, os.Args[2:])
+		case "start":
+			runCLI(cmdStart, os.Args[2:])
+		case "kill":
+			runCLI(cmdKill, os.Args[2:])
+		case "status":
+			runCLI(cmdStatus, os.Args[2:])
+		case "preview":
+			runCLI(cmdPreview, os.Args[2:])
 		case "--version", "-v":
 			fmt.Println("warpp v1.0.0")
 			return
@@ -650,11 +1044,22 @@ func main() {
 			printHelp()
 			return
 		case "--new", "-n":
-			launchNewSession()
+			if err := launchNewSession(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 			return
 		}
 	}
 
+	runTUI("", false)
+}
+
+// runTUI runs the session list in one of the display modes until a session
+// is launched or the user quits. With no mode the config chooses between
+// inline and full screen. demo shows demoBackend's sessions instead of
+// tmux's.
+func runTUI(mode string, demo bool) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -662,26 +1067,157 @@ func main() {
 		cfg = config.DefaultConfig()
 	}
 
-	// Get theme from config
+	var backend tmux.Backend
+	if demo {
+		backend = demoBackend()
+	} else {
+		// Keep one tmux control-mode client open for live session and pane data
+		control := tmux.NewControlBackend()
+		defer control.Close()
+		backend = control
+	}
+
+	if mode == "" {
+		mode = modeFullscreen
+		if cfg.Inline {
+			mode = modeInline
+		}
+	}
+
+	m := newModel(cfg, backend)
+	if demo {
+		// Leave the daemon, rules and launch history of the real sessions alone
+		m.demo = true
+		m.daemon = nil
+		m.rules = nil
+		m.history = nil
+	}
+	m.compact = mode == modePopup
+	m.inline = mode == modeInline
+	var opts []tea.ProgramOption
+	if !m.inline {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	final, err := tea.NewProgram(m, opts...).Run()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch mode {
+	case modeInline:
+		// The list is gone; attach now that the terminal is ours again
+		if start := final.(simpleModel).pending; start != nil {
+			if err := start(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	case modeFullscreen:
+		// Clear screen on exit for clean terminal
+		fmt.Print("\033[H\033[2J")
+	}
+}
+
+// newModel sets up the TUI from the configuration, reading tmux through
+// backend
+func newModel(cfg config.Config, backend tmux.Backend) simpleModel {
 	theme := themes.GetTheme(cfg.Theme)
+	agents, engine := setupAgents(cfg)
+
+	// Launch history for frecency ordering; a broken file just starts over
+	hist, _ := history.Load()
+
+	return simpleModel{
+		backend:       backend,
+		sortMode:      cfg.SortMode,
+		showRecent:    cfg.ShowRecent,
+		waitingFirst:  cfg.WaitingFirst,
+		trash:         cfg.Snapshot.Trash,
+		inlineHeight:  cfg.InlineHeight,
+		inlinePreview: cfg.InlinePreview,
+		history:       hist,
+		agents:        agents,
+		rules:         engine,
+		daemon:        daemon.NewClient(),
+		previews:      make(map[string]sessionPreview),
+		expanded:      make(map[string]bool),
+		windows:       make(map[string][]tmux.WindowInfo),
+		theme:         theme,
+		styles:        theme.Styles(),
+		asciiFrames:   themes.GetASCIIArtFrames(cfg.ASCIIArt),
+	}
+}
 
-	// Get ASCII art frames from config
-	asciiFrames := themes.GetASCIIArtFrames(cfg.ASCIIArt)
+// setupAgents registers the coding agents to detect in panes and compiles
+// the rules run when they change state. It returns the enabled agents by
+// name, and a nil engine when a rule is broken.
+func setupAgents(cfg config.Config) (map[string]config.Agent, *rules.Engine) {
+	agents := make(map[string]config.Agent)
+	var detectors []tmux.AgentDetector
+	for _, agent := range cfg.Agents {
+		if agent.Disabled {
+			continue
+		}
+		d, err := tmux.NewAgentDetector(agent.Name, agent.Process, agent.Executing, agent.Idle, agent.Waiting)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Skipping agent: %v\n", err)
+			continue
+		}
+		detectors = append(detectors, d)
+		agents[agent.Name] = agent
+	}
+	tmux.SetAgentDetectors(detectors)
 
-	m := simpleModel{
-		theme:       theme,
-		styles:      theme.Styles(),
-		asciiFrames: asciiFrames,
+	engine, err := rules.New(cfg.Rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Skipping rules: %v\n", err)
 	}
+	return agents, engine
+}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+// handleDaemonCommand runs the background daemon until interrupted, or
+// stops a running one
+func handleDaemonCommand(args []string) {
+	if len(args) == 1 && args[0] == "stop" {
+		if err := daemon.NewClient().Shutdown(context.Background()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 {
+		fmt.Println("Usage: warpp daemon [stop]")
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: Could not load config, using defaults: %v\n", err)
+		cfg = config.DefaultConfig()
+	}
+	_, engine := setupAgents(cfg)
+
+	path := daemon.SocketPath()
+	listener, err := daemon.Listen(path)
+	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	defer os.Remove(path)
 
-	// Clear screen on exit for clean terminal
-	fmt.Print("\033[H\033[2J")
+	backend := tmux.NewControlBackend()
+	defer backend.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("warpp daemon listening on %s\n", path)
+	if minutes := cfg.Snapshot.Autosave; minutes > 0 {
+		go autosave(ctx, cfg, time.Duration(minutes)*time.Minute)
+	}
+	if err := daemon.NewServer(backend, engine).Serve(ctx, listener); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
 }
 
 func handleConfigCommand() {
@@ -704,15 +1240,141 @@ func handleTestASCII() {
 	fmt.Println(frames[0])
 }
 
+// handleConvertCommand prints a tmuxifier layout as a native session spec
+func handleConvertCommand(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: warpp convert <layout>")
+		os.Exit(1)
+	}
+
+	home, _ := os.UserHomeDir()
+	layoutPath := filepath.Join(home, ".tmuxifier", "layouts", args[0]+".session.sh")
+	parsed, err := tmux.ParseTmuxifierLayout(layoutPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := parsed.Spec.YAML()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, warning := range parsed.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	fmt.Print(string(data))
+}
+
+// handleValidateCommand checks every layout and exits non-zero if any has issues
+func handleValidateCommand() {
+	layouts, err := tmux.GetLayouts()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, layout := range layouts {
+		if len(layout.LayoutIssues) == 0 {
+			fmt.Printf("ok    %s (%s)\n", layout.Name, layout.Source)
+			continue
+		}
+		failed = true
+		fmt.Printf("issue %s (%s)\n", layout.Name, layout.Source)
+		for _, issue := range layout.LayoutIssues {
+			fmt.Printf("      %s\n", issue)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// handleHookCommand records the agent state reported by a Claude Code hook,
+// or installs or removes warpp's hooks in the Claude settings
+func handleHookCommand(args []string) {
+	if len(args) == 1 && (args[0] == "install" || args[0] == "uninstall") {
+		path, err := hooks.SettingsPath()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if args[0] == "uninstall" {
+			removed, err := hooks.Uninstall(path)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Removed warpp hooks for %d events from %s\n", removed, path)
+			return
+		}
+		command, err := hooks.Command()
+		if err == nil {
+			err = hooks.Install(path, command)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Installed warpp hooks for %s in %s\n", strings.Join(hooks.Events, ", "), path)
+		return
+	}
+	if len(args) > 1 {
+		fmt.Println("Usage: warpp hook [event | install | uninstall]")
+		os.Exit(1)
+	}
+
+	// A failing hook must never get in the agent's way: report and exit 0
+	event := ""
+	if len(args) == 1 {
+		event = args[0]
+	}
+	if err := hooks.Run(context.Background(), "claude", event, os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "warpp hook: %v\n", err)
+	}
+}
+
 func printHelp() {
 	fmt.Println("warpp - Warp into your tmux sessions")
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  warpp                  Launch the TUI interface")
+	fmt.Println("  warpp --inline, -i     Launch a compact list below the prompt")
+	fmt.Println("  warpp --fullscreen     Launch the full-screen TUI even if inline is configured")
 	fmt.Println("  warpp --new, -n        Create new session in current directory")
+	fmt.Println("  warpp --demo           Try the TUI on example sessions, without tmux")
+	fmt.Println("  warpp ls [--json] [--running|--layouts]")
+	fmt.Println("                         List sessions and layouts")
+	fmt.Println("  warpp attach <name>    Attach to a session, starting its layout if needed")
+	fmt.Println("  warpp start <layout>   Start a layout's session without attaching")
+	fmt.Println("  warpp kill <name...>   Kill running sessions")
+	fmt.Println("  warpp status <name> [--json]")
+	fmt.Println("                         Show whether a session runs and what its agents do")
+	fmt.Println("  warpp preview <name> [--pane N] [--json]")
+	fmt.Println("                         Print the visible content of a session's pane")
+	fmt.Println("  warpp pick [--running|--layouts] [--query Q] [--format target|name|json] [--inline]")
+	fmt.Println("                         Choose a session, window or pane and print it")
+	fmt.Println("  warpp statusline [--session NAME] [--list]")
+	fmt.Println("                         Print agent states for the tmux status line")
+	fmt.Println("  warpp snapshot [--scrollback N] [--list]")
+	fmt.Println("                         Save all running sessions to restore later")
+	fmt.Println("  warpp restore [snapshot] [--session NAME] [--resume-agents|--no-resume]")
+	fmt.Println("                         Recreate the sessions of the latest or given snapshot")
+	fmt.Println("  warpp trash [--json]   List killed sessions that can be brought back")
+	fmt.Println("  warpp trash restore [name] [--resume-agents|--no-resume]")
+	fmt.Println("                         Restore the last killed session, or the named one")
+	fmt.Println("  warpp popup            Launch a compact TUI for tmux display-popup")
+	fmt.Println("  warpp install-tmux-bindings [--key K] [--width W] [--height H] [--print]")
+	fmt.Println("                         Bind prefix+K to open warpp in a tmux popup")
 	fmt.Println("  warpp config           Show current configuration")
 	fmt.Println("  warpp init-config      Create default config file")
 	fmt.Println("  warpp test-ascii       Test current ASCII art setting")
+	fmt.Println("  warpp convert <name>   Print a tmuxifier layout as a session spec")
+	fmt.Println("  warpp validate         Check all layouts for problems")
+	fmt.Println("  warpp daemon [stop]    Track sessions in the background for other warpp commands")
+	fmt.Println("  warpp hook install     Report Claude Code's state through its hooks")
+	fmt.Println("  warpp hook uninstall   Remove warpp's Claude Code hooks")
 	fmt.Println("  warpp --help           Show this help")
 	fmt.Println("  warpp --version        Show version")
 	fmt.Println()
@@ -721,60 +1383,54 @@ func printHelp() {
 	fmt.Println("Available ASCII art: fire, blocks, minimal")
 }
 
-func launchSession(sessionName string) {
-	// Check if this is a tmuxifier layout or orphan session
-	home, _ := os.UserHomeDir()
-	layoutPath := filepath.Join(home, ".tmuxifier", "layouts", sessionName+".session.sh")
-
-	if _, err := os.Stat(layoutPath); err == nil {
-		// Has a layout file - use tmuxifier
-		cmd := exec.Command("tmuxifier", "load-session", sessionName)
-		cmd.Run()
+// layoutSummary returns a short window/pane count for a layout row
+func layoutSummary(session tmux.Session) string {
+	if len(session.LayoutIssues) > 0 {
+		return "⚠"
 	}
-	// If no layout, session already exists - just attach
-
-	// Attach/switch to session
-	var args []string
-	if os.Getenv("TMUX") != "" {
-		args = []string{"tmux", "switch-client", "-t", sessionName}
-	} else {
-		args = []string{"tmux", "attach-session", "-t", sessionName}
+	if session.Layout == nil {
+		return ""
 	}
-
-	tmuxPath, _ := exec.LookPath("tmux")
-	syscall.Exec(tmuxPath, args, os.Environ())
+	return fmt.Sprintf("%dw %dp", session.Layout.WindowCount(), session.Layout.PaneCount())
 }
 
-func launchWorktreeSession(layoutName, sessionName, worktreePath string) {
-	// Set environment variables to override session_root and session name
-	os.Setenv("SESSION_ROOT", worktreePath)
-	os.Setenv("SESSION_NAME", sessionName)
+// launchSession starts the session from its layout if it isn't running yet,
+// then attaches to it. On success it does not return.
+func launchSession(session tmux.Session) error {
+	if session.IsLayout {
+		if err := tmux.StartLayout(context.Background(), session, "", ""); err != nil {
+			return err
+		}
+	} else if session.Source == tmux.SourceTmuxifier {
+		// tmuxifier load-session is a no-op for a running session; keep calling
+		// it so layouts that attach hooks on load still see it
+		if err := tmux.LoadTmuxifierSession(context.Background(), session.Name, session.Name); err != nil {
+			return err
+		}
+	}
+	// Otherwise the session already exists - just attach
 
-	// Load the layout using bash -c to ensure env is passed
-	cmd := exec.Command("bash", "-c",
-		fmt.Sprintf("SESSION_ROOT=%q SESSION_NAME=%q tmuxifier load-session %s", worktreePath, sessionName, layoutName))
-	cmd.Env = os.Environ()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Run()
+	return attachSession(session.Name)
+}
 
-	// Attach to the session
-	var args []string
-	if os.Getenv("TMUX") != "" {
-		args = []string{"tmux", "switch-client", "-t", sessionName}
-	} else {
-		args = []string{"tmux", "attach-session", "-t", sessionName}
+// launchWorktreeSession starts a layout rooted at a worktree under a new
+// session name, then attaches to it. On success it does not return.
+func launchWorktreeSession(layout tmux.Session, sessionName, worktreePath string) error {
+	if err := tmux.StartLayout(context.Background(), layout, sessionName, worktreePath); err != nil {
+		return err
 	}
 
-	tmuxPath, _ := exec.LookPath("tmux")
-	syscall.Exec(tmuxPath, args, os.Environ())
+	return attachSession(sessionName)
 }
 
-func launchNewSession() {
+// launchNewSession creates a session for the current directory from the
+// new-session layout, then attaches to it. On success it does not return.
+// A native new-session spec is preferred over tmuxifier's, and with neither
+// a plain single-window session is created.
+func launchNewSession() error {
 	cwd, err := os.Getwd()
 	if err != nil {
-		fmt.Printf("Error getting current directory: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error getting current directory: %w", err)
 	}
 	sessionName := filepath.Base(cwd)
 
@@ -782,26 +1438,100 @@ func launchNewSession() {
 	sessionName = strings.TrimPrefix(sessionName, ".")
 	sessionName = strings.ReplaceAll(sessionName, ":", "-")
 
-	// Set environment variables in current process so they're inherited
-	os.Setenv("NEW_SESSION_NAME", sessionName)
-	os.Setenv("NEW_SESSION_ROOT", cwd)
+	home, _ := os.UserHomeDir()
+	tmuxifierLayout := filepath.Join(home, ".tmuxifier", "layouts", "new-session.session.sh")
+
+	spec, err := tmux.FindSpec("new-session")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if spec != nil {
+		if err := spec.Start(context.Background(), sessionName, cwd); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(tmuxifierLayout); err == nil {
+		// Load the new-session layout from the current directory
+		if err := tmux.LoadTmuxifierSession(context.Background(), "new-session", sessionName,
+			"NEW_SESSION_NAME="+sessionName, "NEW_SESSION_ROOT="+cwd); err != nil {
+			return err
+		}
+	} else {
+		spec := &tmux.Spec{Name: sessionName, Root: cwd}
+		if err := spec.Start(context.Background(), "", ""); err != nil {
+			return err
+		}
+	}
+
+	return attachSession(sessionName)
+}
+
+// killedMsg reports a kill started from the list
+type killedMsg struct {
+	name string
+	err  error
+}
 
-	// Load the new-session layout using bash -c to ensure env is passed
-	cmd := exec.Command("bash", "-c",
-		fmt.Sprintf("cd %q && tmuxifier load-session new-session", cwd))
-	cmd.Env = os.Environ()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Run()
+// killSessionCmd kills a session with killSession in the background, since
+// saving its scrollback to the trash takes a while
+func killSessionCmd(backend tmux.Backend, client *daemon.Client, name string, trash int) tea.Cmd {
+	return func() tea.Msg {
+		return killedMsg{name: name, err: killSession(backend, client, name, trash)}
+	}
+}
+
+// restoredMsg reports an undone kill
+type restoredMsg struct {
+	name string
+	err  error
+}
+
+// undoKillCmd brings a killed session back with undoKill in the background
+func undoKillCmd(backend tmux.Backend, name string) tea.Cmd {
+	return func() tea.Msg {
+		return restoredMsg{name: name, err: undoKill(backend, name)}
+	}
+}
+
+// killSession saves a running session to the trash, so the kill can be
+// undone, and kills it: through the daemon when it's running, and otherwise
+// directly. Nothing is killed when the session can't be saved.
+func killSession(backend tmux.Backend, client *daemon.Client, name string, trash int) error {
+	ctx := context.Background()
+	s, err := snapshot.TakeSession(ctx, backend, name, trashScrollback)
+	if err == nil {
+		err = snapshot.AddToTrash(*s, trash)
+	}
+	if err != nil {
+		return fmt.Errorf("saving %s to the trash: %w", name, err)
+	}
+
+	err = client.Kill(ctx, name)
+	if errors.Is(err, daemon.ErrUnavailable) {
+		return backend.KillSession(ctx, name)
+	}
+	return err
+}
+
+// attachSession replaces this process with tmux attached to the session,
+// or switches the current client when already inside tmux
+func attachSession(sessionName string) error {
+	// Best-effort: a history that can't be written mustn't block attaching
+	history.Record(sessionName)
 
-	// Attach to the session
 	var args []string
 	if os.Getenv("TMUX") != "" {
-		args = []string{"tmux", "switch-client", "-t", sessionName}
+		args = []string{"tmux", "switch-client", "-t", "=" + sessionName}
 	} else {
-		args = []string{"tmux", "attach-session", "-t", sessionName}
+		args = []string{"tmux", "attach-session", "-t", "=" + sessionName}
 	}
 
-	tmuxPath, _ := exec.LookPath("tmux")
-	syscall.Exec(tmuxPath, args, os.Environ())
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		return &tmux.CommandError{Name: "tmux", Args: args[1:], Kind: tmux.ErrNotInstalled, Err: err}
+	}
+	if err := syscall.Exec(tmuxPath, args, os.Environ()); err != nil {
+		return &tmux.CommandError{Name: "tmux", Args: args[1:], Err: err}
+	}
+	return nil
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"warpp/internal/config"
+	"warpp/internal/tmux"
+)
+
+// Candidate filters of pick mode
+const (
+	pickRunning = "running"
+	pickLayouts = "layouts"
+)
+
+// Output formats of pick mode
+const (
+	pickFormatTarget = "target" // exact tmux target, e.g. =api:1.2
+	pickFormatName   = "name"   // session or layout name
+	pickFormatJSON   = "json"   // pickJSON
+)
+
+// exitCancelled is pick's exit code when nothing was chosen, as in fzf
+const exitCancelled = 130
+
+// filterSessions keeps the running sessions or the layouts when only is
+// pickRunning or pickLayouts
+func filterSessions(sessions []tmux.Session, only string) []tmux.Session {
+	if only == "" {
+		return sessions
+	}
+	var kept []tmux.Session
+	for _, s := range sessions {
+		if (only == pickRunning && s.IsRunning) || (only == pickLayouts && !s.IsRunning && s.IsLayout) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// pickJSON is the stable JSON form of a picked row
+type pickJSON struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Window  *int   `json:"window"` // null unless a window or pane was picked
+	Pane    *int   `json:"pane"`   // null unless a pane was picked
+	PaneID  string `json:"pane_id,omitempty"`
+	Target  string `json:"target"`
+}
+
+// pickTarget returns the tmux target of a row: the session, or its window
+// or pane. The = makes tmux match the session name exactly rather than as
+// a prefix, and the : lets a session row be used where tmux expects a
+// window or pane, as in send-keys -t.
+func pickTarget(row listRow) string {
+	switch {
+	case row.pane != nil:
+		return fmt.Sprintf("=%s:%d.%d", row.session.Name, row.window.Index, row.pane.Index)
+	case row.window != nil:
+		return fmt.Sprintf("=%s:%d", row.session.Name, row.window.Index)
+	}
+	return "=" + row.session.Name + ":"
+}
+
+// formatPick renders a picked row in one of the pick formats
+func formatPick(row listRow, format string) (string, error) {
+	switch format {
+	case pickFormatName:
+		return row.session.Name, nil
+	case pickFormatJSON:
+		out := pickJSON{Name: row.session.Name, Running: row.session.IsRunning, Target: pickTarget(row)}
+		if row.window != nil {
+			out.Window = &row.window.Index
+		}
+		if row.pane != nil {
+			out.Pane = &row.pane.Index
+			out.PaneID = row.pane.ID
+		}
+		data, err := json.Marshal(out)
+		return string(data), err
+	}
+	return pickTarget(row), nil
+}
+
+// cmdPick runs the list on the terminal as a picker and prints the choice
+// to stdout: warpp pick [--running|--layouts] [--query Q]
+// [--format target|name|json] [--inline|--fullscreen]. Nothing is attached
+// or started.
+func cmdPick(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"running", "layouts", "query", "format", "inline", "fullscreen"}, "query", "format")
+	if err != nil {
+		return err
+	}
+	format := flags["format"]
+	switch format {
+	case "":
+		format = pickFormatTarget
+	case pickFormatTarget, pickFormatName, pickFormatJSON:
+	default:
+		return usageError("--format: expected target, name or json, got %q", format)
+	}
+	if len(positional) > 0 || (flags["running"] != "" && flags["layouts"] != "") || (flags["inline"] != "" && flags["fullscreen"] != "") {
+		return usageError("usage: warpp pick [--running|--layouts] [--query Q] [--format target|name|json] [--inline|--fullscreen]")
+	}
+
+	// Draw on the terminal so stdout only carries the choice
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("pick needs a terminal: %w", err)
+	}
+	defer tty.Close()
+	lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	backend := tmux.NewControlBackend()
+	defer backend.Close()
+
+	m := newModel(cfg, backend)
+	m.picking = true
+	m.inline = flags["inline"] != "" || (cfg.Inline && flags["fullscreen"] == "")
+	switch {
+	case flags["running"] != "":
+		m.only = pickRunning
+	case flags["layouts"] != "":
+		m.only = pickLayouts
+	}
+	if query := flags["query"]; query != "" {
+		m.searching = true
+		m.query = query
+	}
+
+	opts := []tea.ProgramOption{tea.WithInput(tty), tea.WithOutput(tty)}
+	if !m.inline {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	final, err := tea.NewProgram(m, opts...).Run()
+	if err != nil {
+		return err
+	}
+	picked := final.(simpleModel).picked
+	if picked == nil {
+		return &cliError{code: exitCancelled}
+	}
+	out, err := formatPick(*picked, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
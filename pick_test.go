@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"warpp/internal/tmux"
+)
+
+func TestFormatPick(t *testing.T) {
+	session := tmux.Session{Name: "api", IsRunning: true}
+	window := &tmux.WindowInfo{Index: 1}
+	pane := &tmux.PaneInfo{Index: 2, ID: "%7"}
+	layout := tmux.Session{Name: "web", IsLayout: true}
+
+	tests := []struct {
+		name   string
+		row    listRow
+		format string
+		want   string
+	}{
+		{name: "session target", row: listRow{session: session}, format: pickFormatTarget, want: "=api:"},
+		{name: "window target", row: listRow{session: session, window: window}, format: pickFormatTarget, want: "=api:1"},
+		{name: "pane target", row: listRow{session: session, window: window, pane: pane}, format: pickFormatTarget, want: "=api:1.2"},
+		{name: "pane name", row: listRow{session: session, window: window, pane: pane}, format: pickFormatName, want: "api"},
+		{
+			name:   "session json",
+			row:    listRow{session: session},
+			format: pickFormatJSON,
+			want:   `{"name":"api","running":true,"window":null,"pane":null,"target":"=api:"}`,
+		},
+		{
+			name:   "pane json",
+			row:    listRow{session: session, window: window, pane: pane},
+			format: pickFormatJSON,
+			want:   `{"name":"api","running":true,"window":1,"pane":2,"pane_id":"%7","target":"=api:1.2"}`,
+		},
+		{
+			name:   "layout json",
+			row:    listRow{session: layout},
+			format: pickFormatJSON,
+			want:   `{"name":"web","running":false,"window":null,"pane":null,"target":"=web:"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatPick(tt.row, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
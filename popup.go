@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"warpp/internal/tmux"
+)
+
+// cmdInstallTmuxBindings binds a prefix key to open warpp popup:
+// warpp install-tmux-bindings [--key K] [--width W] [--height H] [--print].
+// The binding is written to ~/.config/warpp/tmux.conf, which the user's
+// tmux.conf sources; --print only prints the snippet.
+func cmdInstallTmuxBindings(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"key", "width", "height", "print"}, "key", "width", "height")
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError("usage: warpp install-tmux-bindings [--key K] [--width W] [--height H] [--print]")
+	}
+	binding := tmux.Binding{Key: "W", Width: "80%", Height: "75%"}
+	if flags["key"] != "" {
+		binding.Key = flags["key"]
+	}
+	if flags["width"] != "" {
+		binding.Width = flags["width"]
+	}
+	if flags["height"] != "" {
+		binding.Height = flags["height"]
+	}
+	for _, value := range []string{binding.Key, binding.Width, binding.Height} {
+		if strings.ContainsAny(value, " \t\n;'\"") {
+			return usageError("invalid binding value %q", value)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(exe, " '\"") {
+		exe = "'" + strings.ReplaceAll(exe, "'", `'\''`) + "'"
+	}
+	snippet := tmux.BindingSnippet(binding, exe+" popup")
+	if flags["print"] != "" {
+		fmt.Print(snippet)
+		return nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	snippetPath := filepath.Join(home, ".config", "warpp", "tmux.conf")
+	confPath, err := tmux.ConfPath()
+	if err != nil {
+		return err
+	}
+	changed, err := tmux.InstallSnippet(confPath, snippetPath, snippet)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", snippetPath)
+	if changed {
+		fmt.Printf("Sourced it from %s\n", confPath)
+	} else {
+		fmt.Printf("%s already sources it\n", confPath)
+	}
+
+	// Load the binding into a running server right away
+	if _, err := tmux.Run(context.Background(), "tmux", "source-file", snippetPath); err == nil {
+		fmt.Printf("Press prefix + %s to open warpp\n", binding.Key)
+	} else {
+		fmt.Printf("Press prefix + %s to open warpp once tmux reloads its config\n", binding.Key)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"warpp/internal/tmux"
+)
+
+const (
+	// previewInterval is how often the selected session's preview is re-captured
+	previewInterval = 500 * time.Millisecond
+	// previewDebounce delays capture after a cursor move so scrolling through
+	// the list doesn't start a capture per row
+	previewDebounce = 80 * time.Millisecond
+)
+
+// sessionPreview is the cached preview of a running session
+type sessionPreview struct {
+	panes      []tmux.PaneInfo // panes with Content filled in
+	capturedAt time.Time
+	stale      bool  // last capture failed; panes hold the previous content
+	err        error // why the last capture failed
+}
+
+// previewTickMsg asks for a capture of the selected session
+type previewTickMsg struct {
+	seq int
+}
+
+// previewMsg carries the result of a background capture
+type previewMsg struct {
+	seq     int
+	session string
+	panes   []tmux.PaneInfo
+	err     error
+}
+
+// schedulePreview cancels any in-flight capture and schedules a new one for
+// the selected session after delay
+func (m *simpleModel) schedulePreview(delay time.Duration) tea.Cmd {
+	if m.previewCancel != nil {
+		m.previewCancel()
+		m.previewCancel = nil
+	}
+	m.previewSeq++
+
+	seq := m.previewSeq
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return previewTickMsg{seq: seq}
+	})
+}
+
+// startPreview starts a background capture of the selected session, if it's running
+func (m *simpleModel) startPreview() tea.Cmd {
+	row, ok := m.selectedRow()
+	if !ok {
+		return nil
+	}
+	selected := row.session
+	if !selected.IsRunning {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.previewCancel = cancel
+	return capturePreviewCmd(ctx, m.backend, selected.Name, m.panelHeight(), m.previewSeq)
+}
+
+// storePreview caches a capture result. A failed capture keeps the previous
+// content and marks it stale.
+func (m *simpleModel) storePreview(msg previewMsg) {
+	if msg.err != nil {
+		cached := m.previews[msg.session]
+		cached.stale = true
+		cached.err = msg.err
+		m.previews[msg.session] = cached
+		return
+	}
+
+	m.previews[msg.session] = sessionPreview{
+		panes:      msg.panes,
+		capturedAt: time.Now(),
+	}
+}
+
+// capturePreviewCmd lists a session's panes and captures each one, stopping
+// early if ctx is cancelled because the cursor moved on
+func capturePreviewCmd(ctx context.Context, backend tmux.Backend, sessionName string, totalHeight int, seq int) tea.Cmd {
+	return func() tea.Msg {
+		panes, err := backend.ListPanes(ctx, sessionName)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return previewMsg{seq: seq, session: sessionName, err: err}
+		}
+
+		heightPerPane := paneCaptureHeight(totalHeight, len(panes))
+		for i := range panes {
+			content, err := backend.CapturePane(ctx, sessionName, panes[i].Index, heightPerPane, true)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return previewMsg{seq: seq, session: sessionName, err: err}
+			}
+			panes[i].Content = content
+		}
+
+		return previewMsg{seq: seq, session: sessionName, panes: panes}
+	}
+}
+
+// paneCaptureHeight splits the preview height between stacked panes,
+// accounting for the separators between them
+func paneCaptureHeight(totalHeight, numPanes int) int {
+	if numPanes < 1 {
+		numPanes = 1
+	}
+	separatorLines := numPanes - 1
+	heightPerPane := (totalHeight - separatorLines) / numPanes
+	if heightPerPane < 3 {
+		heightPerPane = 3
+	}
+	return heightPerPane
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"warpp/internal/tmux"
+)
+
+func TestCapturePreviewCmd(t *testing.T) {
+	b := tmux.NewFakeBackend()
+	b.AddSession("web",
+		tmux.PaneInfo{Index: 0, Width: 60, Height: 40, Active: true},
+		tmux.PaneInfo{Index: 1, Left: 61, Width: 59, Height: 40},
+	)
+	b.SetPaneContent("web", 0, "1\n2\n3\n4\n5\n6\n7\n8")
+	b.SetPaneContent("web", 1, "npm run dev")
+
+	tests := []struct {
+		name     string
+		session  string
+		height   int
+		contents []string
+		wantErr  error
+	}{
+		{name: "panes share the height", session: "web", height: 9, contents: []string{"5\n6\n7\n8", "npm run dev"}},
+		{name: "at least three lines per pane", session: "web", height: 2, contents: []string{"6\n7\n8", "npm run dev"}},
+		{name: "session gone", session: "api", height: 9, wantErr: tmux.ErrSessionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := capturePreviewCmd(context.Background(), b, tt.session, tt.height, 7)().(previewMsg)
+			if !ok {
+				t.Fatal("no previewMsg")
+			}
+			if msg.seq != 7 || msg.session != tt.session {
+				t.Errorf("seq, session = %d, %q", msg.seq, msg.session)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(msg.err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", msg.err, tt.wantErr)
+				}
+				return
+			}
+			if msg.err != nil {
+				t.Fatal(msg.err)
+			}
+			var contents []string
+			for _, pane := range msg.panes {
+				contents = append(contents, pane.Content)
+			}
+			if len(contents) != len(tt.contents) {
+				t.Fatalf("got %d panes, want %d", len(contents), len(tt.contents))
+			}
+			for i := range contents {
+				if contents[i] != tt.contents[i] {
+					t.Errorf("pane %d: got %q, want %q", i, contents[i], tt.contents[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCapturePreviewCmdCancelled(t *testing.T) {
+	b := tmux.NewFakeBackend()
+	b.AddSession("web")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if msg := capturePreviewCmd(ctx, b, "web", 10, 1)(); msg != nil {
+		t.Errorf("got %#v after the cursor moved on, want nil", msg)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"warpp/internal/config"
+	"warpp/internal/snapshot"
+	"warpp/internal/tmux"
+)
+
+// errNothingRunning is returned when there are no sessions to snapshot
+var errNothingRunning = &cliError{code: exitNotRunning, err: errors.New("no running sessions to snapshot")}
+
+// takeSnapshot snapshots the running sessions, saves the snapshot and
+// deletes old ones
+func takeSnapshot(ctx context.Context, cfg config.Config, scrollback int) (*snapshot.Snapshot, string, error) {
+	snap, err := snapshot.Take(ctx, tmux.NewExecBackend(), scrollback)
+	if errors.Is(err, tmux.ErrNoServer) || (err == nil && len(snap.Sessions) == 0) {
+		return nil, "", errNothingRunning
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	path, err := snapshot.Save(snap)
+	if err != nil {
+		return nil, "", err
+	}
+	return snap, path, snapshot.Prune(cfg.Snapshot.Keep)
+}
+
+// autosave snapshots the running sessions every interval until ctx is done.
+// Nothing is saved while no session runs, so the last snapshot survives the
+// tmux server going away.
+func autosave(ctx context.Context, cfg config.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := takeSnapshot(ctx, cfg, cfg.Snapshot.Scrollback); err != nil && err != errNothingRunning {
+				fmt.Fprintf(os.Stderr, "autosave: %v\n", err)
+			}
+		}
+	}
+}
+
+// plural counts n of noun, e.g. "1 pane" or "3 panes"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// snapshotSummary describes a snapshot's sessions, e.g. "2 sessions (3
+// windows, 5 panes)"
+func snapshotSummary(sessions []snapshot.Session) string {
+	windows, panes := 0, 0
+	for _, s := range sessions {
+		windows += len(s.Windows)
+		panes += s.PaneCount()
+	}
+	return fmt.Sprintf("%s (%s, %s)", plural(len(sessions), "session"), plural(windows, "window"), plural(panes, "pane"))
+}
+
+// cmdSnapshot saves the running sessions to ~/.config/warpp/snapshots:
+// warpp snapshot [--scrollback N] [--list]. --list shows the saved
+// snapshots instead, newest first.
+func cmdSnapshot(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"scrollback", "list"}, "scrollback")
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError("usage: warpp snapshot [--scrollback N] [--list]")
+	}
+
+	if flags["list"] != "" {
+		paths, err := snapshot.List()
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			snap, err := snapshot.Load(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			var names []string
+			for _, s := range snap.Sessions {
+				names = append(names, s.Name)
+			}
+			fmt.Printf("%s  %s: %s\n", strings.TrimSuffix(filepath.Base(path), ".json"), snapshotSummary(snap.Sessions), strings.Join(names, ", "))
+		}
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	scrollback := cfg.Snapshot.Scrollback
+	if value, ok := flags["scrollback"]; ok {
+		if scrollback, err = strconv.Atoi(value); err != nil || scrollback < 0 {
+			return usageError("--scrollback: expected a number of lines, got %q", value)
+		}
+	}
+	setupAgents(cfg)
+
+	snap, path, err := takeSnapshot(context.Background(), cfg, scrollback)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s to %s\n", snapshotSummary(snap.Sessions), path)
+	return nil
+}
+
+// loadSnapshot reads the snapshot named by arg, a path or a name from
+// warpp snapshot --list, or the latest one when arg is empty
+func loadSnapshot(arg string) (*snapshot.Snapshot, error) {
+	if arg == "" {
+		snap, _, err := snapshot.Latest()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &cliError{code: exitNotFound, err: errors.New("no snapshots yet; take one with warpp snapshot")}
+		}
+		return snap, err
+	}
+	if _, err := os.Stat(arg); err != nil {
+		dir, dirErr := snapshot.Dir()
+		if dirErr != nil {
+			return nil, dirErr
+		}
+		arg = filepath.Join(dir, strings.TrimSuffix(arg, ".json")+".json")
+	}
+	snap, err := snapshot.Load(arg)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &cliError{code: exitNotFound, err: fmt.Errorf("no snapshot %s", arg)}
+	}
+	return snap, err
+}
+
+// cmdRestore recreates the sessions of a snapshot that aren't running:
+// warpp restore [snapshot] [--session NAME] [--resume-agents|--no-resume].
+// Panes that ran a coding agent can re-run the agent's resume command:
+// restore asks on a terminal, and otherwise only with --resume-agents.
+// Other commands are listed but never re-run.
+func cmdRestore(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"session", "resume-agents", "no-resume"}, "session")
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 || (flags["resume-agents"] != "" && flags["no-resume"] != "") {
+		return usageError("usage: warpp restore [snapshot] [--session NAME] [--resume-agents|--no-resume]")
+	}
+	var arg string
+	if len(positional) == 1 {
+		arg = positional[0]
+	}
+	snap, err := loadSnapshot(arg)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	ctx := context.Background()
+	running := make(map[string]bool)
+	infos, err := tmux.NewExecBackend().ListSessions(ctx)
+	if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+		return err
+	}
+	for _, info := range infos {
+		running[info.Name] = true
+	}
+
+	found := false
+	var failed error
+	for _, s := range snap.Sessions {
+		if name, ok := flags["session"]; ok && s.Name != name {
+			continue
+		}
+		found = true
+		if running[s.Name] {
+			fmt.Printf("%s is already running\n", s.Name)
+			continue
+		}
+		if err := restoreSession(ctx, s, cfg, flags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: restoring %s: %v\n", s.Name, err)
+			failed = &cliError{code: exitError}
+		}
+	}
+
+	if name, ok := flags["session"]; ok && !found {
+		return &cliError{code: exitNotFound, err: fmt.Errorf("no session named %s in the snapshot", name)}
+	}
+	return failed
+}
+
+// restoreSession restores a snapshotted session and prints what it did.
+// Panes that ran a coding agent re-run the agent's resume command when the
+// user agrees on a terminal or flags has resume-agents, unless it has
+// no-resume. Other commands are only listed.
+func restoreSession(ctx context.Context, s snapshot.Session, cfg config.Config, flags map[string]string) error {
+	resume := make(map[string]string)
+	for _, agent := range cfg.Agents {
+		resume[agent.Name] = agent.Resume
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	interactive := isatty.IsTerminal(os.Stdin.Fd())
+
+	var notes []string
+	err := snapshot.Restore(ctx, s, func(w snapshot.Window, p snapshot.Pane) string {
+		target := fmt.Sprintf("%s:%d.%d", s.Name, w.Index, p.Index)
+		command := resume[p.Agent]
+		switch {
+		case p.Agent == "" || command == "":
+			if p.Command != "" {
+				notes = append(notes, fmt.Sprintf("  %s ran: %s", target, p.Command))
+			}
+			return ""
+		case flags["resume-agents"] != "":
+			return command
+		case flags["no-resume"] == "" && interactive:
+			fmt.Printf("Run %q in %s (%s)? [y/N] ", command, target, shortenHome(p.Path))
+			answer, _ := stdin.ReadString('\n')
+			if strings.EqualFold(strings.TrimSpace(answer), "y") {
+				return command
+			}
+		}
+		notes = append(notes, fmt.Sprintf("  %s ran %s; resume it with: %s", target, p.Agent, command))
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s (%s, %s)\n", s.Name, plural(len(s.Windows), "window"), plural(s.PaneCount(), "pane"))
+	for _, note := range notes {
+		fmt.Println(note)
+	}
+	return nil
+}
+
+// trashScrollback is how many lines of history are kept per pane of a
+// killed session
+const trashScrollback = 1000
+
+// untrash restores the most recently killed session named name, or any
+// when name is empty, that isn't running again, and takes it out of the
+// trash. It returns the session's name.
+func untrash(backend tmux.Backend, name string, restore func(snapshot.Session) error) (string, error) {
+	trash, err := snapshot.ListTrash()
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	running := make(map[string]bool)
+	infos, err := backend.ListSessions(ctx)
+	if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+		return "", err
+	}
+	for _, info := range infos {
+		running[info.Name] = true
+	}
+
+	for _, t := range trash {
+		if (name != "" && t.Session.Name != name) || running[t.Session.Name] {
+			continue
+		}
+		if err := restore(t.Session); err != nil {
+			return "", err
+		}
+		return t.Session.Name, t.Remove()
+	}
+	if name != "" {
+		return "", &cliError{code: exitNotFound, err: fmt.Errorf("no killed session named %s in the trash that isn't running", name)}
+	}
+	return "", &cliError{code: exitNotFound, err: errors.New("nothing in the trash to restore")}
+}
+
+// undoKill restores the last killed session named name, unless it's
+// running again. No command is re-run.
+func undoKill(backend tmux.Backend, name string) error {
+	_, err := untrash(backend, name, func(s snapshot.Session) error {
+		return snapshot.Restore(context.Background(), s, func(snapshot.Window, snapshot.Pane) string { return "" })
+	})
+	return err
+}
+
+// trashJSON is the JSON form of a killed session printed by warpp trash
+type trashJSON struct {
+	Name    string    `json:"name"`
+	Killed  time.Time `json:"killed"`
+	Windows int       `json:"windows"`
+	Panes   int       `json:"panes"`
+	Agents  []string  `json:"agents"`
+}
+
+// cmdTrash lists the killed sessions kept in the trash, newest first, or
+// brings one back: warpp trash [--json], warpp trash restore [name]
+// [--resume-agents|--no-resume]. Without a name the last killed session
+// is restored.
+func cmdTrash(args []string) error {
+	if len(args) > 0 && args[0] == "restore" {
+		flags, positional, err := parseFlags(args[1:], []string{"resume-agents", "no-resume"})
+		if err != nil {
+			return err
+		}
+		if len(positional) > 1 || (flags["resume-agents"] != "" && flags["no-resume"] != "") {
+			return usageError("usage: warpp trash restore [name] [--resume-agents|--no-resume]")
+		}
+		var name string
+		if len(positional) == 1 {
+			name = positional[0]
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		_, err = untrash(tmux.NewExecBackend(), name, func(s snapshot.Session) error {
+			return restoreSession(context.Background(), s, cfg, flags)
+		})
+		return err
+	}
+
+	flags, positional, err := parseFlags(args, []string{"json"})
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError("usage: warpp trash [--json] | warpp trash restore [name] [--resume-agents|--no-resume]")
+	}
+	trash, err := snapshot.ListTrash()
+	if err != nil {
+		return err
+	}
+
+	out := []trashJSON{}
+	for _, t := range trash {
+		entry := trashJSON{
+			Name:    t.Session.Name,
+			Killed:  t.Killed,
+			Windows: len(t.Session.Windows),
+			Panes:   t.Session.PaneCount(),
+			Agents:  []string{},
+		}
+		for _, w := range t.Session.Windows {
+			for _, p := range w.Panes {
+				if p.Agent != "" {
+					entry.Agents = append(entry.Agents, p.Agent)
+				}
+			}
+		}
+		out = append(out, entry)
+	}
+	if flags["json"] != "" {
+		return printJSON(out)
+	}
+	for _, entry := range out {
+		killed := relativeTime(entry.Killed, time.Now())
+		if killed != "now" {
+			killed += " ago"
+		}
+		line := fmt.Sprintf("%-20s killed %-8s %dw %dp", entry.Name, killed, entry.Windows, entry.Panes)
+		if len(entry.Agents) > 0 {
+			line += "  " + strings.Join(entry.Agents, ", ")
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
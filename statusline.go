@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"warpp/internal/config"
+	"warpp/internal/daemon"
+	"warpp/internal/themes"
+	"warpp/internal/tmux"
+)
+
+// statuslineIcons mark agent states in the status line
+var statuslineIcons = map[string]string{
+	tmux.AgentWaiting:   "⚠",
+	tmux.AgentExecuting: "✻",
+	tmux.AgentIdle:      "●",
+}
+
+// statuslineCacheTTL is how long agent states read straight from tmux are
+// reused. tmux redraws the status line of every client at once, and each
+// redraw would otherwise list every pane and scan the processes again.
+const statuslineCacheTTL = 2 * time.Second
+
+// statuslineAgents returns the agents of each running session: from the
+// daemon when it's running, and otherwise straight from tmux, through a
+// short-lived cache
+func statuslineAgents(ctx context.Context, cfg config.Config) map[string][]tmux.AgentState {
+	if sessions, err := daemon.NewClient().List(ctx); err == nil {
+		agents := make(map[string][]tmux.AgentState)
+		for _, s := range sessions {
+			if s.IsRunning && len(s.Agents) > 0 {
+				agents[s.Name] = s.Agents
+			}
+		}
+		return agents
+	}
+
+	path, err := statuslineCachePath()
+	if err == nil {
+		if agents, ok := readStatuslineCache(path, time.Now()); ok {
+			return agents
+		}
+	}
+	setupAgents(cfg)
+	agents := tmux.GetAgentStates(ctx, tmux.NewExecBackend())
+	if err == nil {
+		// Best-effort: the next call just asks tmux again
+		writeStatuslineCache(path, agents)
+	}
+	return agents
+}
+
+// statuslineCachePath returns the cache file for the tmux server warpp
+// talks to, named after a hash of its socket: the one in $TMUX inside tmux,
+// and otherwise the default one
+func statuslineCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	socket, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+	if socket == "" {
+		dir := os.Getenv("TMUX_TMPDIR")
+		if dir == "" {
+			dir = "/tmp"
+		}
+		socket = filepath.Join(dir, fmt.Sprintf("tmux-%d", os.Getuid()), "default")
+	}
+	h := fnv.New64a()
+	h.Write([]byte(socket))
+	return filepath.Join(home, ".config", "warpp", "cache", fmt.Sprintf("statusline-%016x.json", h.Sum64())), nil
+}
+
+// readStatuslineCache returns the cached agent states if the cache was
+// written less than statuslineCacheTTL before now
+func readStatuslineCache(path string, now time.Time) (map[string][]tmux.AgentState, bool) {
+	info, err := os.Stat(path)
+	if err != nil || now.Sub(info.ModTime()) >= statuslineCacheTTL || info.ModTime().After(now) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var agents map[string][]tmux.AgentState
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return nil, false
+	}
+	return agents, true
+}
+
+// writeStatuslineCache replaces the cache atomically, so status lines
+// drawn at the same time never read a partial file
+func writeStatuslineCache(path string, agents map[string][]tmux.AgentState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(agents)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "statusline-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// tmuxStyled wraps text in a tmux style with foreground color, escaping #
+func tmuxStyled(text, color string) string {
+	text = strings.ReplaceAll(text, "#", "##")
+	if color == "" {
+		return text
+	}
+	return fmt.Sprintf("#[fg=%s]%s#[default]", color, text)
+}
+
+// statuslineCounts summarises agents as counts per state, most urgent
+// first, e.g. ⚠1 ✻2
+func statuslineCounts(agents map[string][]tmux.AgentState, theme themes.Theme) string {
+	counts := make(map[string]int)
+	for _, list := range agents {
+		for _, agent := range list {
+			counts[agent.State]++
+		}
+	}
+	colors := map[string]string{
+		tmux.AgentWaiting:   theme.Warning,
+		tmux.AgentExecuting: theme.Primary,
+		tmux.AgentIdle:      theme.Muted,
+	}
+	var parts []string
+	for _, state := range []string{tmux.AgentWaiting, tmux.AgentExecuting, tmux.AgentIdle} {
+		if counts[state] > 0 {
+			parts = append(parts, tmuxStyled(fmt.Sprintf("%s%d", statuslineIcons[state], counts[state]), colors[state]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// statuslineSessions lists the sessions running agents with the icon of
+// the one that most needs attention, e.g. ⚠ api ● web
+func statuslineSessions(agents map[string][]tmux.AgentState, theme themes.Theme, cfg config.Config) string {
+	icons := make(map[string]config.Agent)
+	for _, agent := range cfg.Agents {
+		icons[agent.Name] = agent
+	}
+
+	var names []string
+	for name := range agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		top, ok := tmux.Session{Agents: agents[name]}.TopAgent()
+		if !ok {
+			continue
+		}
+		icon, color := icons[top.Agent].Icon, icons[top.Agent].Color
+		if icon == "" {
+			icon = statuslineIcons[tmux.AgentIdle]
+		}
+		switch top.State {
+		case tmux.AgentWaiting:
+			icon, color = statuslineIcons[tmux.AgentWaiting], theme.Warning
+		case tmux.AgentIdle:
+			color = theme.Muted
+		}
+		parts = append(parts, tmuxStyled(icon, color)+" "+tmuxStyled(name, ""))
+	}
+	return strings.Join(parts, " ")
+}
+
+// cmdStatusline prints agent states in tmux's format syntax for the status
+// line: warpp statusline [--session NAME] [--list]. By default it counts
+// agents per state; --list names the sessions instead, and --session only
+// looks at one. It prints nothing when no agent runs.
+func cmdStatusline(args []string) error {
+	flags, positional, err := parseFlags(args, []string{"session", "list"}, "session")
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usageError("usage: warpp statusline [--session NAME] [--list]")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	theme := themes.GetTheme(cfg.Theme)
+
+	agents := statuslineAgents(context.Background(), cfg)
+	if name, ok := flags["session"]; ok {
+		agents = map[string][]tmux.AgentState{name: agents[name]}
+	}
+
+	if flags["list"] != "" {
+		fmt.Println(statuslineSessions(agents, theme, cfg))
+	} else {
+		fmt.Println(statuslineCounts(agents, theme))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"warpp/internal/tmux"
+)
+
+func TestStatuslineCachePath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_TMPDIR", "")
+
+	paths := make(map[string]string)
+	for _, env := range []string{
+		"/tmp/tmux-1000/default,4242,0",
+		"/tmp/tmux-1000/default,4242,3", // another session on the same server
+		"/tmp/tmux-1000/work,5151,0",
+		"",
+	} {
+		t.Setenv("TMUX", env)
+		path, err := statuslineCachePath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[env] = path
+	}
+	if paths["/tmp/tmux-1000/default,4242,0"] != paths["/tmp/tmux-1000/default,4242,3"] {
+		t.Error("sessions of one server use different caches")
+	}
+	if paths["/tmp/tmux-1000/default,4242,0"] == paths["/tmp/tmux-1000/work,5151,0"] {
+		t.Error("two servers share a cache")
+	}
+	if paths[""] == paths["/tmp/tmux-1000/work,5151,0"] {
+		t.Error("the default server shares a cache with another")
+	}
+}
+
+func TestStatuslineCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "statusline.json")
+	agents := map[string][]tmux.AgentState{
+		"api": {{Agent: "claude", State: tmux.AgentWaiting, Pane: 1, PaneID: "%3"}},
+	}
+	if _, ok := readStatuslineCache(path, time.Now()); ok {
+		t.Fatal("read a cache that was never written")
+	}
+	if err := writeStatuslineCache(path, agents); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := info.ModTime()
+
+	tests := []struct {
+		name string
+		now  time.Time
+		ok   bool
+	}{
+		{name: "fresh", now: written.Add(time.Second), ok: true},
+		{name: "expired", now: written.Add(statuslineCacheTTL), ok: false},
+		{name: "written in the future", now: written.Add(-time.Minute), ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := readStatuslineCache(path, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, agents) {
+				t.Errorf("got %+v, want %+v", got, agents)
+			}
+		})
+	}
+}